@@ -0,0 +1,428 @@
+package asm
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go-evm/evm"
+
+	"github.com/holiman/uint256"
+)
+
+var (
+	// ErrUnknownInstruction is returned when a mnemonic is neither an instruction nor a macro.
+	ErrUnknownInstruction = errors.New("unknown instruction")
+	// ErrUnknownLabel is returned when a label is referenced but never defined.
+	ErrUnknownLabel = errors.New("unknown label")
+	// ErrDuplicateLabel is returned when a label is defined twice.
+	ErrDuplicateLabel = errors.New("duplicate label")
+	// ErrInvalidOperand is returned when an operand can't be parsed, or is given to an instruction without one.
+	ErrInvalidOperand = errors.New("invalid operand")
+	// ErrValueTooLarge is returned when a value doesn't fit in the immediate data of a PUSH instruction.
+	ErrValueTooLarge = errors.New("value too large")
+	// ErrInvalidDirective is returned when a directive is malformed.
+	ErrInvalidDirective = errors.New("invalid directive")
+)
+
+// dataDirective emits raw bytes, e.g. `%data 0x61ff` for a PUSH2 truncated by the end of the code.
+const dataDirective = "%data"
+
+// maxMacroDepth is the maximum number of nested macro expansions, to reject recursive macros.
+const maxMacroDepth = 64
+
+var (
+	identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	pcPrefixPattern   = regexp.MustCompile(`^[0-9a-fA-F]+:\s`)
+)
+
+// item represents an instruction of the assembled code.
+type item struct {
+	opcode evm.OpCode
+
+	// Value of a PUSH instruction, or label whose position it pushes.
+	value *uint256.Int
+	label string
+
+	// True if the size of the PUSH instruction is chosen from its value.
+	autoSize bool
+
+	// Name of the label defined at this position, for label definitions.
+	definition string
+
+	// Raw bytes emitted instead of an instruction, for %data directives.
+	data []byte
+
+	line int
+}
+
+// macro represents a macro defined with %macro.
+type macro struct {
+	params []string
+	body   []sourceLine
+}
+
+// sourceLine represents a line of source with its number, for error messages.
+type sourceLine struct {
+	text string
+	line int
+}
+
+// assembler holds the state of an assembly.
+type assembler struct {
+	constants map[string]*uint256.Int
+	macros    map[string]*macro
+	items     []*item
+}
+
+// Assemble converts assembly source into bytecode.
+//
+// Each line holds one statement, and comments start with ';' or '//':
+//
+//	PUSH1 0x80          ; instruction with explicit immediate data
+//	PUSH 1000           ; PUSH with the smallest size holding the value
+//	@loop               ; label definition, emitting a JUMPDEST
+//	PUSH @loop          ; label reference, resolved once the code is laid out
+//	JUMPI(@loop, 1)     ; pushes the arguments in reverse order, so the first one is on top, then JUMPI
+//	MSTORE(0x40, ADD(1, 2))
+//	%define SIZE 0x20   ; constant, usable as a value
+//	%macro store(offset, value)
+//	MSTORE(offset, value)
+//	%endmacro
+//	store(0, SIZE)      ; macro invocation
+//	%data 0x61ff        ; raw bytes, e.g. a PUSH2 truncated by the end of the code
+//
+// The output of the disassembler is accepted, so code can be disassembled, edited and assembled back.
+func Assemble(source string) ([]byte, error) {
+	a := &assembler{constants: make(map[string]*uint256.Int), macros: make(map[string]*macro)}
+	lines := strings.Split(source, "\n")
+	var sourceLines []sourceLine
+	for i := 0; i < len(lines); i++ {
+		text := cleanLine(lines[i])
+		if name, ok := strings.CutPrefix(text, "%macro"); ok {
+			end, err := a.defineMacro(name, lines, i)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			i = end
+			continue
+		}
+		sourceLines = append(sourceLines, sourceLine{text: text, line: i + 1})
+	}
+	for _, line := range sourceLines {
+		if err := a.statement(line, 0); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line.line, err)
+		}
+	}
+	return a.layout()
+}
+
+// Remove the comments, the program counter printed by the disassembler, and the surrounding whitespace.
+func cleanLine(line string) string {
+	if i := strings.Index(line, ";"); i >= 0 {
+		line = line[:i]
+	}
+	if i := strings.Index(line, "//"); i >= 0 {
+		line = line[:i]
+	}
+	line = strings.TrimSpace(line)
+	if loc := pcPrefixPattern.FindStringIndex(line); loc != nil {
+		line = strings.TrimSpace(line[loc[1]:])
+	}
+	return line
+}
+
+// Parse the macro defined on the given line, and return the line of its %endmacro.
+func (a *assembler) defineMacro(signature string, lines []string, start int) (int, error) {
+	name, params, isCall := parseCall(strings.TrimSpace(signature))
+	if !isCall {
+		name, params = strings.TrimSpace(signature), nil
+	}
+	if !identifierPattern.MatchString(name) {
+		return 0, fmt.Errorf("%w: invalid macro name %q", ErrInvalidDirective, name)
+	}
+	m := &macro{params: params}
+	for i := start + 1; i < len(lines); i++ {
+		text := cleanLine(lines[i])
+		if text == "%endmacro" {
+			a.macros[name] = m
+			return i, nil
+		}
+		m.body = append(m.body, sourceLine{text: text, line: i + 1})
+	}
+	return 0, fmt.Errorf("%w: missing %%endmacro", ErrInvalidDirective)
+}
+
+// Assemble a statement.
+func (a *assembler) statement(line sourceLine, depth int) error {
+	text := line.text
+	switch {
+	case text == "":
+		return nil
+	case strings.HasPrefix(text, "%define"):
+		fields := strings.Fields(text)
+		if len(fields) != 3 || !identifierPattern.MatchString(fields[1]) {
+			return fmt.Errorf("%w: expected %%define <name> <value>", ErrInvalidDirective)
+		}
+		value, err := a.value(fields[2])
+		if err != nil {
+			return err
+		}
+		a.constants[fields[1]] = value
+		return nil
+	case strings.HasPrefix(text, dataDirective):
+		fields := strings.Fields(text)
+		digits, ok := "", len(fields) == 2 && fields[0] == dataDirective
+		if ok {
+			digits, ok = strings.CutPrefix(strings.ToLower(fields[1]), "0x")
+		}
+		data, err := hex.DecodeString(digits)
+		if !ok || err != nil || len(data) == 0 {
+			return fmt.Errorf("%w: expected %s <hex bytes>", ErrInvalidDirective, dataDirective)
+		}
+		a.items = append(a.items, &item{data: data, line: line.line})
+		return nil
+	case strings.HasPrefix(text, "@") && identifierPattern.MatchString(text[1:]):
+		a.items = append(a.items, &item{opcode: evm.JUMPDEST, definition: text[1:], line: line.line})
+		return nil
+	}
+
+	if name, args, ok := parseCall(text); ok {
+		if m, ok := a.macros[name]; ok {
+			return a.expandMacro(m, args, line, depth)
+		}
+	}
+
+	// An instruction with an optional operand, or an expression.
+	mnemonic, operand, _ := strings.Cut(text, " ")
+	operand = strings.TrimSpace(operand)
+	opcode, ok := LookupOpCode(mnemonic)
+	if strings.EqualFold(mnemonic, "PUSH") {
+		return a.push(operand, 0, line.line)
+	}
+	if ok && opcode >= evm.PUSH1 && opcode <= evm.PUSH32 {
+		return a.push(operand, immediateSize(defaultInstructions, opcode), line.line)
+	}
+	if ok && operand != "" {
+		return fmt.Errorf("%w: %s doesn't take an operand", ErrInvalidOperand, mnemonic)
+	}
+	return a.expression(text, line.line)
+}
+
+// Expand a macro invocation, replacing its parameters with the arguments.
+func (a *assembler) expandMacro(m *macro, args []string, line sourceLine, depth int) error {
+	if depth >= maxMacroDepth {
+		return fmt.Errorf("%w: too many nested macros", ErrInvalidDirective)
+	}
+	if len(args) != len(m.params) {
+		return fmt.Errorf("%w: expected %d macro arguments, got %d", ErrInvalidOperand, len(m.params), len(args))
+	}
+	for _, bodyLine := range m.body {
+		text := bodyLine.text
+		for i, param := range m.params {
+			text = regexp.MustCompile(`\b`+regexp.QuoteMeta(param)+`\b`).ReplaceAllLiteralString(text, args[i])
+		}
+		if err := a.statement(sourceLine{text: text, line: line.line}, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Assemble an expression: a call like ADD(1, 2), pushing its arguments in reverse order, a value to push, or an instruction without operand.
+func (a *assembler) expression(text string, line int) error {
+	text = strings.TrimSpace(text)
+	if name, args, ok := parseCall(text); ok {
+		opcode, ok := LookupOpCode(name)
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownInstruction, name)
+		}
+		for i := len(args) - 1; i >= 0; i-- {
+			if err := a.expression(args[i], line); err != nil {
+				return err
+			}
+		}
+		a.items = append(a.items, &item{opcode: opcode, line: line})
+		return nil
+	}
+	if _, ok := a.constants[text]; ok || strings.HasPrefix(text, "@") || isNumber(text) {
+		return a.push(text, 0, line)
+	}
+	opcode, ok := LookupOpCode(text)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownInstruction, text)
+	}
+	if immediateSize(defaultInstructions, opcode) > 0 {
+		return fmt.Errorf("%w: %s expects an operand", ErrInvalidOperand, text)
+	}
+	a.items = append(a.items, &item{opcode: opcode, line: line})
+	return nil
+}
+
+// Add a PUSH instruction of the given size, or of the smallest size holding the value if the size is 0.
+func (a *assembler) push(operand string, size int, line int) error {
+	if operand == "" {
+		return fmt.Errorf("%w: PUSH expects an operand", ErrInvalidOperand)
+	}
+	it := &item{opcode: evm.PUSH1 + evm.OpCode(max(size, 1)-1), autoSize: size == 0, line: line}
+	if label, ok := strings.CutPrefix(operand, "@"); ok {
+		if !identifierPattern.MatchString(label) {
+			return fmt.Errorf("%w: invalid label %q", ErrInvalidOperand, operand)
+		}
+		it.label = label
+		a.items = append(a.items, it)
+		return nil
+	}
+	value, err := a.value(operand)
+	if err != nil {
+		return err
+	}
+	if err := it.setValue(value); err != nil {
+		return err
+	}
+	a.items = append(a.items, it)
+	return nil
+}
+
+// Parse a number or a constant.
+func (a *assembler) value(operand string) (*uint256.Int, error) {
+	if value, ok := a.constants[operand]; ok {
+		return value, nil
+	}
+	var value *uint256.Int
+	var err error
+	if digits, ok := strings.CutPrefix(strings.ToLower(operand), "0x"); ok {
+		// FromHex rejects leading zeros, which are common in immediate data.
+		if digits = strings.TrimLeft(digits, "0"); digits == "" && len(operand) > 2 {
+			value = new(uint256.Int)
+		} else {
+			value, err = uint256.FromHex("0x" + digits)
+		}
+	} else {
+		value, err = uint256.FromDecimal(operand)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidOperand, operand)
+	}
+	return value, nil
+}
+
+// Set the value pushed by the instruction, choosing its size if it is automatic.
+func (it *item) setValue(value *uint256.Int) error {
+	it.value = value
+	size := max((value.BitLen()+7)/8, 1)
+	if it.autoSize {
+		it.opcode = evm.PUSH1 + evm.OpCode(size-1)
+	} else if size > immediateSize(defaultInstructions, it.opcode) {
+		return fmt.Errorf("%w: %#x doesn't fit in %s", ErrValueTooLarge, value, Mnemonic(it.opcode))
+	}
+	return nil
+}
+
+// Return the number of bytes of the encoded item.
+func (it *item) size() int {
+	if it.data != nil {
+		return len(it.data)
+	}
+	return 1 + immediateSize(defaultInstructions, it.opcode)
+}
+
+// Resolve the labels and encode the instructions.
+// Automatically sized label references start with one byte and grow until the positions of all the labels are stable.
+func (a *assembler) layout() ([]byte, error) {
+	labels := make(map[string]int)
+	for {
+		pc := 0
+		for _, it := range a.items {
+			if it.definition != "" {
+				labels[it.definition] = pc
+			}
+			pc += it.size()
+		}
+
+		stable := true
+		for _, it := range a.items {
+			if it.label == "" {
+				continue
+			}
+			position, ok := labels[it.label]
+			if !ok {
+				return nil, fmt.Errorf("line %d: %w: @%s", it.line, ErrUnknownLabel, it.label)
+			}
+			previous := it.opcode
+			if err := it.setValue(uint256.NewInt(uint64(position))); err != nil {
+				return nil, fmt.Errorf("line %d: %w", it.line, err)
+			}
+			stable = stable && it.opcode == previous
+		}
+		if stable {
+			break
+		}
+	}
+
+	// Check the labels are unique, and encode the instructions.
+	defined := make(map[string]bool)
+	var code []byte
+	for _, it := range a.items {
+		if it.definition != "" {
+			if defined[it.definition] {
+				return nil, fmt.Errorf("line %d: %w: @%s", it.line, ErrDuplicateLabel, it.definition)
+			}
+			defined[it.definition] = true
+		}
+		if it.data != nil {
+			code = append(code, it.data...)
+			continue
+		}
+		code = append(code, byte(it.opcode))
+		if size := immediateSize(defaultInstructions, it.opcode); size > 0 {
+			data := it.value.Bytes32()
+			code = append(code, data[32-size:]...)
+		}
+	}
+	return code, nil
+}
+
+// Parse a call like NAME(arg1, arg2), splitting the arguments on the top-level commas.
+func parseCall(text string) (name string, args []string, ok bool) {
+	open := strings.Index(text, "(")
+	if open <= 0 || !strings.HasSuffix(text, ")") {
+		return "", nil, false
+	}
+	name = strings.TrimSpace(text[:open])
+	if !identifierPattern.MatchString(name) {
+		return "", nil, false
+	}
+	inner := text[open+1 : len(text)-1]
+	if strings.TrimSpace(inner) == "" {
+		return name, nil, true
+	}
+	depth, start := 0, 0
+	for i, c := range inner {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return "", nil, false
+			}
+		case ',':
+			if depth == 0 {
+				args = append(args, strings.TrimSpace(inner[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return "", nil, false
+	}
+	return name, append(args, strings.TrimSpace(inner[start:])), true
+}
+
+// Return true if the text starts like a number literal.
+func isNumber(text string) bool {
+	return text != "" && text[0] >= '0' && text[0] <= '9'
+}
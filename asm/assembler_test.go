@@ -0,0 +1,137 @@
+package asm
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestAssemble(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{
+			name:     "instructions",
+			source:   "PUSH1 0x80\npush1 0x40 ; comment\n\nMSTORE // comment\nPUSH2 1\nUNDEFINED_0x0c",
+			expected: "60806040526100010c",
+		},
+		{
+			name:     "automatic push sizing",
+			source:   "PUSH 0\nPUSH 255\nPUSH 256\nPUSH 0x000102",
+			expected: "600060ff610100610102",
+		},
+		{
+			name:     "calls",
+			source:   "MSTORE(0x40, ADD(1, 2))\nRETURN(0, 32)",
+			expected: "600260010160405260206000f3",
+		},
+		{
+			name:     "labels",
+			source:   "PUSH @end\nJUMP\n@loop\nJUMPI(@loop, 1)\n@end\nSTOP",
+			expected: "6009565b60016003575b00",
+		},
+		{
+			name:     "constants",
+			source:   "%define SIZE 0x20\n%define OFFSET 64\nMSTORE(OFFSET, SIZE)\nPUSH2 SIZE",
+			expected: "6020604052610020",
+		},
+		{
+			name:     "macros",
+			source:   "%macro store(offset, value)\nPUSH value\nPUSH offset\nMSTORE\n%endmacro\n%macro zero\nPUSH 0\n%endmacro\nstore(0, 0xff)\nzero()",
+			expected: "60ff6000526000",
+		},
+		{
+			name:     "disassembler output",
+			source:   "0000: PUSH1 0x80\n0002: PUSH1 0x40\n0004: MSTORE",
+			expected: "6080604052",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			code, err := Assemble(test.source)
+			if err != nil {
+				t.Fatalf("Assemble() returned an unexpected error: %v", err)
+			}
+			if expected := common.FromHex(test.expected); !bytes.Equal(code, expected) {
+				t.Errorf("Expected code %x, got %x", expected, code)
+			}
+		})
+	}
+}
+
+func TestAssembleGrowsLabelReferences(t *testing.T) {
+	// The label is beyond 255 once the reference is laid out, so the reference needs two bytes.
+	source := "JUMP(@end)\n" + strings.Repeat("STOP\n", 253) + "@end"
+	code, err := Assemble(source)
+	if err != nil {
+		t.Fatalf("Assemble() returned an unexpected error: %v", err)
+	}
+	if !bytes.Equal(code[:3], []byte{0x61, 0x01, 0x01}) || len(code) != 258 || code[257] != 0x5b {
+		t.Errorf("Unexpected code: %x", code)
+	}
+}
+
+func TestAssembleErrors(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected error
+	}{
+		{"FOO", ErrUnknownInstruction},
+		{"ADD(1, FOO)", ErrUnknownInstruction},
+		{"JUMP(@missing)", ErrUnknownLabel},
+		{"@a\n@a", ErrDuplicateLabel},
+		{"PUSH1 0x100", ErrValueTooLarge},
+		{"PUSH1", ErrInvalidOperand},
+		{"PUSH1 0xzz", ErrInvalidOperand},
+		{"ADD 1", ErrInvalidOperand},
+		{"%define X", ErrInvalidDirective},
+		{"%macro m\nSTOP", ErrInvalidDirective},
+		{"%macro m\nm()\n%endmacro\nm()", ErrInvalidDirective},
+		{"%data", ErrInvalidDirective},
+		{"%data 0x", ErrInvalidDirective},
+		{"%data 0x123", ErrInvalidDirective},
+		{"%data 60", ErrInvalidDirective},
+	}
+	for _, test := range tests {
+		if _, err := Assemble(test.source); !errors.Is(err, test.expected) {
+			t.Errorf("Assemble(%q) returned an unexpected error: %v, wanted: %v", test.source, err, test.expected)
+		}
+	}
+	if _, err := Assemble("STOP\nFOO"); err == nil || !strings.HasPrefix(err.Error(), "line 2: ") {
+		t.Errorf("The error should include the line number, got: %v", err)
+	}
+}
+
+func TestAssembleTruncatedRoundTrip(t *testing.T) {
+	// PUSH1 1, PUSH2 0xff (truncated), an undefined opcode followed by PUSH1 (truncated), and PUSH32 (truncated).
+	for _, code := range [][]byte{{0x60, 0x01, 0x61, 0xff}, {0x0c, 0x60}, {0x7f}} {
+		assembled, err := Assemble(Format(code))
+		if err != nil {
+			t.Fatalf("Assemble() returned an unexpected error for %x: %v", code, err)
+		}
+		if !bytes.Equal(assembled, code) {
+			t.Errorf("The code doesn't round-trip:\n%x\n%x", code, assembled)
+		}
+	}
+}
+
+func TestAssembleRoundTrip(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		code := make([]byte, random.Intn(200))
+		random.Read(code)
+		assembled, err := Assemble(Format(code))
+		if err != nil {
+			t.Fatalf("Assemble() returned an unexpected error for %x: %v", code, err)
+		}
+		if !bytes.Equal(assembled, code) {
+			t.Fatalf("The code doesn't round-trip:\n%x\n%x", code, assembled)
+		}
+	}
+}
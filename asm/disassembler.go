@@ -0,0 +1,95 @@
+// Package asm converts EVM bytecode to and from a human-readable assembly language.
+package asm
+
+import (
+	"fmt"
+	"strings"
+
+	"go-evm/evm"
+)
+
+// Instruction represents a decoded instruction.
+type Instruction struct {
+	// Position of the instruction in the code.
+	PC int
+
+	OpCode   evm.OpCode
+	Mnemonic string
+
+	// Data following the opcode, e.g. the value of a PUSH instruction.
+	// It is shorter than expected if the code ends in the middle of the data.
+	Immediate []byte
+
+	// Number of bytes of immediate data expected by the instruction.
+	immediateSize int
+}
+
+// Truncated returns true if the code ends before the end of the immediate data of the instruction.
+func (i Instruction) Truncated() bool {
+	return len(i.Immediate) < i.immediateSize
+}
+
+// String formats the instruction like `0000: PUSH1 0x80`.
+// A truncated instruction is formatted as the raw bytes left in the code, like `0000: %data 0x61ff`, followed by a comment, so it assembles back to the same bytes.
+func (i Instruction) String() string {
+	if i.Truncated() {
+		return fmt.Sprintf("%04x: %s %#x ; truncated %s, %d of %d bytes", i.PC, dataDirective, append([]byte{byte(i.OpCode)}, i.Immediate...), i.Mnemonic, len(i.Immediate), i.immediateSize)
+	}
+	s := fmt.Sprintf("%04x: %s", i.PC, i.Mnemonic)
+	if i.immediateSize > 0 {
+		s += fmt.Sprintf(" %#x", i.Immediate)
+	}
+	return s
+}
+
+// disassembler holds the configuration of Disassemble.
+type disassembler struct {
+	instructions *evm.InstructionSet
+}
+
+// DisassemblerOption represents a function that configures the disassembler.
+type DisassemblerOption func(*disassembler)
+
+// WithInstructionSet decodes the code with the given instruction set, e.g. the one of an EVM with custom operations.
+// The instruction set of the latest fork is used by default.
+func WithInstructionSet(set *evm.InstructionSet) DisassemblerOption {
+	return func(d *disassembler) {
+		d.instructions = set
+	}
+}
+
+// Disassemble splits the code into instructions.
+// Their names and immediate data sizes are read from the instruction set.
+// Opcodes missing from the instruction set are named by their standard mnemonic, without immediate data.
+func Disassemble(code []byte, opts ...DisassemblerOption) []Instruction {
+	d := &disassembler{instructions: defaultInstructions}
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	var instructions []Instruction
+	for pc := 0; pc < len(code); pc++ {
+		opcode := evm.OpCode(code[pc])
+		instruction := Instruction{PC: pc, OpCode: opcode, Mnemonic: Mnemonic(opcode)}
+		if op := d.instructions[opcode]; op != nil {
+			instruction.Mnemonic, instruction.immediateSize = op.Name, op.ImmediateSize
+		}
+		if instruction.immediateSize > 0 {
+			end := min(pc+1+instruction.immediateSize, len(code))
+			instruction.Immediate = append([]byte{}, code[pc+1:end]...)
+			pc = end - 1
+		}
+		instructions = append(instructions, instruction)
+	}
+	return instructions
+}
+
+// Format disassembles the code and formats each instruction on its own line.
+func Format(code []byte, opts ...DisassemblerOption) string {
+	var b strings.Builder
+	for _, instruction := range Disassemble(code, opts...) {
+		b.WriteString(instruction.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
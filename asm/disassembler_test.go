@@ -0,0 +1,121 @@
+package asm
+
+import (
+	"reflect"
+	"testing"
+
+	"go-evm/evm"
+
+	"github.com/holiman/uint256"
+)
+
+func TestDisassemble(t *testing.T) {
+	// PUSH1 0x80, PUSH0, ADD, 0x0c, PUSH2 0x01 (truncated)
+	instructions := Disassemble([]byte{0x60, 0x80, 0x5f, 0x01, 0x0c, 0x61, 0x01})
+	expected := []Instruction{
+		{PC: 0, OpCode: evm.PUSH1, Mnemonic: "PUSH1", Immediate: []byte{0x80}, immediateSize: 1},
+		{PC: 2, OpCode: evm.PUSH0, Mnemonic: "PUSH0"},
+		{PC: 3, OpCode: evm.ADD, Mnemonic: "ADD"},
+		{PC: 4, OpCode: 0x0c, Mnemonic: "UNDEFINED_0x0c"},
+		{PC: 5, OpCode: evm.PUSH1 + 1, Mnemonic: "PUSH2", Immediate: []byte{0x01}, immediateSize: 2},
+	}
+	if !reflect.DeepEqual(instructions, expected) {
+		t.Errorf("Unexpected instructions: %+v, wanted: %+v", instructions, expected)
+	}
+	if !instructions[4].Truncated() || instructions[0].Truncated() {
+		t.Errorf("Only the last PUSH should be truncated")
+	}
+}
+
+func TestDisassembleWithInstructionSet(t *testing.T) {
+	set := evm.NewInstructionSet(evm.LatestFork)
+	err := set.Register(0x0c, &evm.Operation{Name: "PUSHPAIR", StackOut: 2, ImmediateSize: 2, Execute: func(ctx *evm.OperationContext) error { return nil }})
+	if err != nil {
+		t.Fatalf("Register() returned an unexpected error: %v", err)
+	}
+
+	// PUSHPAIR 0x0102, JUMPI, PUSHPAIR 0x03 (truncated)
+	code := []byte{0x0c, 0x01, 0x02, 0x57, 0x0c, 0x03}
+	expected := "0000: PUSHPAIR 0x0102\n0003: JUMPI\n0004: %data 0x0c03 ; truncated PUSHPAIR, 1 of 2 bytes\n"
+	if output := Format(code, WithInstructionSet(set)); output != expected {
+		t.Errorf("Unexpected output:\n%s\nwanted:\n%s", output, expected)
+	}
+
+	// Without the instruction set, the opcode is undefined and its immediate data is decoded as instructions.
+	expected = "0000: UNDEFINED_0x0c\n0001: ADD\n0002: MUL\n0003: JUMPI\n0004: UNDEFINED_0x0c\n0005: SUB\n"
+	if output := Format(code); output != expected {
+		t.Errorf("Unexpected output:\n%s\nwanted:\n%s", output, expected)
+	}
+}
+
+func TestDisassembleRegisteredOperation(t *testing.T) {
+	// SWAPPAIR pushes the 2 bytes of immediate data following it, in reverse order.
+	code := []byte{0xef, 0x01, 0x02, 0xef, 0x03, 0x04, 0x01}
+	vm := evm.NewEVM(code)
+	err := vm.RegisterOperation(0xef, &evm.Operation{
+		Name:          "SWAPPAIR",
+		StackOut:      1,
+		ImmediateSize: 2,
+		Execute: func(ctx *evm.OperationContext) error {
+			immediate := ctx.Immediate()
+			return ctx.Stack.Push(new(uint256.Int).SetBytes([]byte{immediate[1], immediate[0]}))
+		},
+	})
+	if err != nil {
+		t.Fatalf("RegisterOperation() returned an unexpected error: %v", err)
+	}
+
+	result := vm.Run()
+	if result.Err != nil || len(result.Stack) != 1 || result.Stack[0].Uint64() != 0x0201+0x0403 {
+		t.Fatalf("Run() returned an unexpected result: %v, %v", result.Stack, result.Err)
+	}
+	expected := "0000: SWAPPAIR 0x0102\n0003: SWAPPAIR 0x0304\n0006: ADD\n"
+	if output := Format(code, WithInstructionSet(vm.Instructions())); output != expected {
+		t.Errorf("Unexpected output:\n%s\nwanted:\n%s", output, expected)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	expected := `0000: PUSH1 0x80
+0002: PUSH1 0x40
+0004: MSTORE
+0005: JUMPDEST
+0006: PUSH32 0x00000000000000000000000000000000000000000000000000000000000000ff
+0027: %data 0x620102 ; truncated PUSH3, 2 of 3 bytes
+`
+	code := append([]byte{0x60, 0x80, 0x60, 0x40, 0x52, 0x5b, 0x7f}, make([]byte, 32)...)
+	code[38] = 0xff
+	code = append(code, 0x62, 0x01, 0x02)
+	if output := Format(code); output != expected {
+		t.Errorf("Unexpected output:\n%s\nwanted:\n%s", output, expected)
+	}
+	if output := Format([]byte{0x60}); output != "0000: %data 0x60 ; truncated PUSH1, 0 of 1 bytes\n" {
+		t.Errorf("Unexpected output: %s", output)
+	}
+}
+
+func TestLookupOpCode(t *testing.T) {
+	tests := []struct {
+		mnemonic string
+		opcode   evm.OpCode
+		ok       bool
+	}{
+		{"push32", evm.PUSH32, true},
+		{"SHA3", evm.KECCAK256, true},
+		{"UNDEFINED_0x0c", 0x0c, true},
+		{"UNDEFINED_0c", 0, false},
+		{"FOO", 0, false},
+	}
+	for _, test := range tests {
+		if opcode, ok := LookupOpCode(test.mnemonic); opcode != test.opcode || ok != test.ok {
+			t.Errorf("LookupOpCode(%q) returned %#x, %t, wanted %#x, %t", test.mnemonic, opcode, ok, test.opcode, test.ok)
+		}
+	}
+
+	// Every opcode should round-trip through its mnemonic.
+	for i := 0; i < 256; i++ {
+		if opcode, ok := LookupOpCode(Mnemonic(evm.OpCode(i))); !ok || opcode != evm.OpCode(i) {
+			t.Errorf("Opcode %#x doesn't round-trip through %s", i, Mnemonic(evm.OpCode(i)))
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package asm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go-evm/evm"
+)
+
+// defaultInstructions is the instruction set of the latest fork, used when no instruction set is given.
+var defaultInstructions = evm.NewInstructionSet(evm.LatestFork)
+
+// undefinedPrefix is the prefix of the mnemonics of undefined opcodes, followed by the opcode in hexadecimal, e.g. UNDEFINED_0x0c.
+const undefinedPrefix = "UNDEFINED_"
+
+// Mnemonic returns the standard name of the opcode.
+// Undefined opcodes are named after their value, e.g. UNDEFINED_0x0c, so they can be assembled back.
+func Mnemonic(opcode evm.OpCode) string {
+	if name, ok := evm.StandardName(opcode); ok {
+		return name
+	}
+	return fmt.Sprintf("%s%#02x", undefinedPrefix, byte(opcode))
+}
+
+// LookupOpCode returns the opcode of a mnemonic, ignoring the case.
+func LookupOpCode(mnemonic string) (evm.OpCode, bool) {
+	if opcode, ok := evm.LookupOpCode(mnemonic); ok {
+		return opcode, true
+	}
+	mnemonic = strings.ToUpper(mnemonic)
+	if value, ok := strings.CutPrefix(mnemonic, undefinedPrefix); ok {
+		if opcode, err := strconv.ParseUint(strings.TrimPrefix(value, "0X"), 16, 8); err == nil && strings.HasPrefix(value, "0X") {
+			return evm.OpCode(opcode), true
+		}
+	}
+	return 0, false
+}
+
+// Return the number of bytes of immediate data following the opcode in the instruction set.
+func immediateSize(instructions *evm.InstructionSet, opcode evm.OpCode) int {
+	if op := instructions[opcode]; op != nil {
+		return op.ImmediateSize
+	}
+	return 0
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"go-evm/asm"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+func init() {
+	commands = append(commands, command{
+		name:        "asm",
+		usage:       "asm [file]",
+		description: "Assemble source from a file or the standard input into hex bytecode",
+		run:         asmCommand,
+	})
+}
+
+func asmCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 1 {
+		fmt.Fprintln(stderr, "Usage: tiny-gevm asm [file]")
+		return 2
+	}
+	var source []byte
+	var err error
+	if len(args) == 1 {
+		source, err = os.ReadFile(args[0])
+	} else {
+		source, err = io.ReadAll(stdin)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	code, err := asm.Assemble(string(source))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	fmt.Fprintln(stdout, hexutil.Encode(code))
+	return 0
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Read bytecode from a hex string, or from a file containing either hex or raw bytes.
+func readCode(arg string) ([]byte, error) {
+	data, err := os.ReadFile(arg)
+	if errors.Is(err, os.ErrNotExist) {
+		code, err := decodeHex(arg)
+		if err != nil {
+			return nil, fmt.Errorf("%q is neither a file nor hex: %w", arg, err)
+		}
+		return code, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if code, err := decodeHex(string(data)); err == nil {
+		return code, nil
+	}
+	return data, nil
+}
+
+// Decode a hex string, with or without the 0x prefix, ignoring whitespace.
+func decodeHex(s string) ([]byte, error) {
+	s = strings.Join(strings.Fields(s), "")
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s = s[2:]
+	}
+	return hex.DecodeString(s)
+}
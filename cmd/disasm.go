@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"go-evm/asm"
+)
+
+func init() {
+	commands = append(commands, command{
+		name:        "disasm",
+		usage:       "disasm <hex|file>",
+		description: "Disassemble bytecode",
+		run:         disasmCommand,
+	})
+}
+
+func disasmCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) != 1 {
+		fmt.Fprintln(stderr, "Usage: tiny-gevm disasm <hex|file>")
+		return 2
+	}
+	code, err := readCode(args[0])
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	fmt.Fprint(stdout, asm.Format(code))
+	return 0
+}
@@ -1,11 +1,49 @@
+// Command tiny-gevm executes and inspects EVM bytecode.
 package main
 
 import (
 	"fmt"
-	"go-evm/evm"
+	"io"
+	"os"
 )
 
+// command represents a subcommand of the CLI.
+// It returns the exit code of the process.
+type command struct {
+	name        string
+	usage       string
+	description string
+	run         func(args []string, stdin io.Reader, stdout, stderr io.Writer) int
+}
+
+var commands []command
+
 func main() {
-	evm := evm.NewEVM(nil)
-	fmt.Println("EVM:", evm)
+	os.Exit(runCommand(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// Run the subcommand named by the first argument.
+func runCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 0 {
+		for _, c := range commands {
+			if c.name == args[0] {
+				return c.run(args[1:], stdin, stdout, stderr)
+			}
+		}
+		if args[0] != "help" && args[0] != "-h" && args[0] != "--help" {
+			fmt.Fprintf(stderr, "unknown command %q\n\n", args[0])
+			printUsage(stderr)
+			return 2
+		}
+	}
+	printUsage(stderr)
+	return 2
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: tiny-gevm <command> [arguments]")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-24s %s\n", c.usage, c.description)
+	}
 }
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Helper function to run the CLI with the given arguments and input.
+func runCLI(t *testing.T, stdin string, args ...string) (stdout, stderr string, code int) {
+	t.Helper()
+	var out, err bytes.Buffer
+	code = runCommand(args, strings.NewReader(stdin), &out, &err)
+	return out.String(), err.String(), code
+}
+
+func TestUnknownCommand(t *testing.T) {
+	_, stderr, code := runCLI(t, "", "foo")
+	if code != 2 || !strings.Contains(stderr, `unknown command "foo"`) || !strings.Contains(stderr, "disasm <hex|file>") {
+		t.Errorf("Unexpected exit code %d and error output:\n%s", code, stderr)
+	}
+}
+
+func TestDisasmCommand(t *testing.T) {
+	expected := "0000: PUSH1 0x80\n0002: MSTORE\n"
+	if stdout, _, code := runCLI(t, "", "disasm", "0x608052"); code != 0 || stdout != expected {
+		t.Errorf("Unexpected exit code %d and output:\n%s", code, stdout)
+	}
+
+	// Files can contain hex or raw bytes.
+	dir := t.TempDir()
+	hexFile, binFile := filepath.Join(dir, "code.hex"), filepath.Join(dir, "code.bin")
+	if err := os.WriteFile(hexFile, []byte("608052\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(binFile, []byte{0x60, 0x80, 0x52}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{hexFile, binFile} {
+		if stdout, _, code := runCLI(t, "", "disasm", file); code != 0 || stdout != expected {
+			t.Errorf("Unexpected exit code %d and output for %s:\n%s", code, file, stdout)
+		}
+	}
+
+	if _, stderr, code := runCLI(t, "", "disasm", "xyz"); code != 1 || !strings.Contains(stderr, "neither a file nor hex") {
+		t.Errorf("Unexpected exit code %d and error output:\n%s", code, stderr)
+	}
+}
+
+func TestAsmCommand(t *testing.T) {
+	if stdout, _, code := runCLI(t, "MSTORE(0x40, 0x80)\n", "asm"); code != 0 || stdout != "0x6080604052\n" {
+		t.Errorf("Unexpected exit code %d and output:\n%s", code, stdout)
+	}
+
+	file := filepath.Join(t.TempDir(), "code.asm")
+	if err := os.WriteFile(file, []byte("@loop\nJUMP(@loop)\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if stdout, _, code := runCLI(t, "", "asm", file); code != 0 || stdout != "0x5b600056\n" {
+		t.Errorf("Unexpected exit code %d and output:\n%s", code, stdout)
+	}
+
+	if _, stderr, code := runCLI(t, "FOO", "asm"); code != 1 || !strings.Contains(stderr, "line 1: unknown instruction: FOO") {
+		t.Errorf("Unexpected exit code %d and error output:\n%s", code, stderr)
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	expected := "output: 0x\ngas used: 9\nstack, from the top:\n  0: 0x3\n"
+	if stdout, _, code := runCLI(t, "", "run", "--code", "0x6001600201"); code != 0 || stdout != expected {
+		t.Errorf("Unexpected exit code %d and output:\n%s", code, stdout)
+	}
+
+	// MSTORE(0, 0x2a) LOG1(0, 32, 0x01) RETURN(31, 1)
+	file := filepath.Join(t.TempDir(), "code.hex")
+	if err := os.WriteFile(file, []byte("602a600052600160206000a16001601ff3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stdout, _, code := runCLI(t, "", "run", "--codefile", file, "--fork", "cancun", "--json")
+	expected = `{
+  "output": "0x2a",
+  "gasUsed": 1033,
+  "gasRefunded": 0,
+  "stack": [],
+  "logs": [
+    {
+      "address": "0x0000000000000000000000000000000000000000",
+      "topics": [
+        "0x0000000000000000000000000000000000000000000000000000000000000001"
+      ],
+      "data": "0x000000000000000000000000000000000000000000000000000000000000002a"
+    }
+  ]
+}
+`
+	if code != 0 || stdout != expected {
+		t.Errorf("Unexpected exit code %d and output:\n%s", code, stdout)
+	}
+}
+
+func TestRunCommandTracer(t *testing.T) {
+	// MSTORE(0, 0x2a) LOG1(0, 32, 1) RETURN(31, 1)
+	code := "0x602a600052600160206000a16001601ff3"
+	_, stderr, exitCode := runCLI(t, "", "run", "--code", code, "--sender", "0x00000000000000000000000000000000000000aa", "--value", "3", "--tracer", "call", "--with-logs")
+	expected := `{"from":"0x00000000000000000000000000000000000000aa","gas":"0x1c9c380","gasUsed":"0x409","to":"0x0000000000000000000000000000000000000000","input":"0x","output":"0x2a",` +
+		`"logs":[{"address":"0x0000000000000000000000000000000000000000","topics":["0x0000000000000000000000000000000000000000000000000000000000000001"],` +
+		`"data":"0x000000000000000000000000000000000000000000000000000000000000002a","position":"0x0"}],"value":"0x3","type":"CALL"}` + "\n"
+	if exitCode != 0 || stderr != expected {
+		t.Errorf("Unexpected exit code %d and trace:\n%s\nwanted:\n%s", exitCode, stderr, expected)
+	}
+
+	// Hex values may have leading zeros.
+	_, stderr, exitCode = runCLI(t, "", "run", "--code", "0x00", "--value", "0x0003", "--tracer", "call")
+	if exitCode != 0 || !strings.Contains(stderr, `"value":"0x3"`) {
+		t.Errorf("Unexpected exit code %d and trace:\n%s", exitCode, stderr)
+	}
+
+	// The json tracer writes each step, followed by a summary.
+	_, stderr, exitCode = runCLI(t, "", "run", "--code", "0x6001600201", "--tracer", "json")
+	if lines := strings.Split(strings.TrimSpace(stderr), "\n"); exitCode != 0 || len(lines) != 5 || lines[4] != `{"output":"","gasUsed":"0x9"}` {
+		t.Errorf("Unexpected exit code %d and trace:\n%s", exitCode, stderr)
+	}
+
+	_, stderr, exitCode = runCLI(t, "", "run", "--code", code, "--tracer", "prestate")
+	if exitCode != 0 || !strings.Contains(stderr, `"code":"0x602a600052600160206000a16001601ff3"`) {
+		t.Errorf("Unexpected exit code %d and trace:\n%s", exitCode, stderr)
+	}
+}
+
+func TestRunCommandSourceMap(t *testing.T) {
+	// The runtime code of Counter without its PUSH1 0, so DUP1 underflows at pc 5.
+	srcmapFlag := "../srcmap/testdata/output.json:Counter.sol:Counter"
+	stdout, _, code := runCLI(t, "", "run", "--code", "0x608060405280fd", "--srcmap", srcmapFlag)
+	if code != 1 || !strings.Contains(stdout, ") at Counter.sol:5:5\n") {
+		t.Errorf("Unexpected exit code %d and output:\n%s", code, stdout)
+	}
+
+	_, stderr, code := runCLI(t, "", "run", "--code", "0x608060405280fd", "--srcmap", srcmapFlag, "--tracer", "json")
+	if lines := strings.Split(stderr, "\n"); code != 1 || !strings.HasSuffix(lines[0], `"opName":"PUSH1","source":"Counter.sol:4:1"}`) {
+		t.Errorf("Unexpected exit code %d and trace:\n%s", code, stderr)
+	}
+
+	for _, flag := range []string{"output.json", "../srcmap/testdata/output.json:Counter.sol", "../srcmap/testdata/output.json:Counter.sol:Token"} {
+		if _, _, code := runCLI(t, "", "run", "--code", "0x00", "--srcmap", flag); code != 2 {
+			t.Errorf("Unexpected exit code %d for --srcmap %s", code, flag)
+		}
+	}
+}
+
+func TestRunCommandFailure(t *testing.T) {
+	// REVERT(0, 0)
+	stdout, _, code := runCLI(t, "", "run", "--code", "0x60006000fd", "--value", "0x10", "--sender", "0x00000000000000000000000000000000000000aa")
+	if code != 1 || !strings.Contains(stdout, "error: execution reverted\n") {
+		t.Errorf("Unexpected exit code %d and output:\n%s", code, stdout)
+	}
+
+	stdout, _, code = runCLI(t, "", "run", "--code", "0x01", "--gas", "100", "--json")
+	if code != 1 || !strings.Contains(stdout, `"gasUsed": 100`) || !strings.Contains(stdout, `"error": "stack underflow`) {
+		t.Errorf("Unexpected exit code %d and output:\n%s", code, stdout)
+	}
+
+	for _, args := range [][]string{
+		{"run"},
+		{"run", "--code", "0x00", "--codefile", "code.hex"},
+		{"run", "--code", "0xzz"},
+		{"run", "--code", "0x00", "--fork", "merge"},
+		{"run", "--code", "0x00", "--value", "-1"},
+		{"run", "--code", "0x00", "--value", "0x"},
+		{"run", "--code", "0x00", "--value", "0x00zz"},
+		{"run", "--code", "0x00", "--sender", "0x01"},
+		{"run", "--code", "0x00", "--tracer", "4byte"},
+		{"run", "--code", "0x00", "--tracer", "json", "--with-logs"},
+	} {
+		if _, _, code := runCLI(t, "", args...); code != 2 {
+			t.Errorf("Unexpected exit code %d for %v", code, args)
+		}
+	}
+}
+
+func TestReplCommand(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "code.hex")
+	if err := os.WriteFile(file, []byte("600052\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	input := strings.Join([]string{
+		"push1 5",
+		"push1 3",
+		"add",
+		"add",
+		":stack",
+		":load " + file,
+		":undo",
+		":mem",
+		"PUSH1 0",
+		"MSTORE",
+		":reset",
+		":undo",
+		":quit",
+		"push1 1",
+	}, "\n")
+	stdout, _, code := runCLI(t, input, "repl")
+	if code != 0 {
+		t.Fatalf("Unexpected exit code %d", code)
+	}
+
+	transcript := strings.TrimPrefix(stdout, replHelp+"> ")
+	expected := []string{
+		"stack, from the top:\n  0: 0x5\nmemory: empty\ngas: 3 used, 29999997 left, last cost 3\n",
+		"stack, from the top:\n  0: 0x3\n  1: 0x5\nmemory: empty\ngas: 6 used, 29999994 left, last cost 3\n",
+		"stack, from the top:\n  0: 0x8\nmemory: empty\ngas: 9 used, 29999991 left, last cost 3\n",
+		"error: stack underflow (pc=5, op=ADD, depth=0, stack=1, required=2, gas=29999991)\n",
+		"stack, from the top:\n  0: 0x8\n",
+		"stack: empty\nmemory:\n  0000: 0000000000000000000000000000000000000000000000000000000000000008\ngas: 18 used, 29999982 left, last cost 9\n",
+		"stack, from the top:\n  0: 0x8\nmemory: empty\ngas: 9 used, 29999991 left\n",
+		"memory: empty\n",
+		"stack, from the top:\n  0: 0x0\n  1: 0x8\nmemory: empty\ngas: 12 used, 29999988 left, last cost 3\n",
+		"stack: empty\nmemory:\n  0000: 0000000000000000000000000000000000000000000000000000000000000008\ngas: 18 used, 29999982 left, last cost 6\n",
+		"stack: empty\nmemory: empty\ngas: 0 used, 30000000 left\n",
+		"nothing to undo\n",
+		"",
+	}
+	if outputs := strings.Split(transcript, "> "); !slices.Equal(outputs, expected) {
+		t.Errorf("Unexpected outputs:\n%q\nwanted:\n%q", outputs, expected)
+	}
+}
+
+func TestReplHalted(t *testing.T) {
+	stdout, _, _ := runCLI(t, "RETURN(0, 0)\npush1 1\n:undo\npush1 1\n", "repl")
+	for _, s := range []string{
+		"halted, output: 0x\n",
+		"error: the execution halted, type :undo or :reset to continue\n",
+		"stack, from the top:\n  0: 0x1\n",
+	} {
+		if !strings.Contains(stdout, s) {
+			t.Errorf("Output doesn't contain %q:\n%s", s, stdout)
+		}
+	}
+}
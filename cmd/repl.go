@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"go-evm/asm"
+	"go-evm/evm"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+func init() {
+	commands = append(commands, command{
+		name:        "repl",
+		usage:       "repl [flags]",
+		description: "Execute instructions interactively",
+		run:         replCommand,
+	})
+}
+
+const replHelp = `Type instructions, like "push1 5" or "add", to execute them.
+Commands:
+  :stack       print the stack
+  :mem         print the memory
+  :reset       clear the state
+  :undo        undo the last instruction or loaded code
+  :load <file> execute the code of a file, in hex or raw bytes
+  :help        print this help
+  :quit        exit
+`
+
+// repl executes the instructions typed by the user on top of the previous ones.
+// The state is rebuilt by replaying the code of every accepted entry, so entries can be undone.
+type repl struct {
+	out  io.Writer
+	gas  uint64
+	opts []evm.Option
+
+	// Code of each accepted entry, in order.
+	entries [][]byte
+	evm     evm.IEVM
+	halted  *evm.ExecutionResult
+}
+
+func replCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("repl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	gas := flags.Uint64("gas", evm.DefaultGasLimit, "gas available to the execution")
+	fork := flags.String("fork", evm.LatestFork.String(), "network upgrade whose rules apply")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintln(stderr, "Usage: tiny-gevm repl [flags]")
+		flags.PrintDefaults()
+		return 2
+	}
+	f, err := evm.ParseFork(*fork)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	r := &repl{out: stdout, gas: *gas, opts: []evm.Option{evm.WithGas(*gas), evm.WithFork(f)}}
+	r.reset()
+	fmt.Fprint(stdout, replHelp)
+	scanner := bufio.NewScanner(stdin)
+	for fmt.Fprint(stdout, "> "); scanner.Scan(); fmt.Fprint(stdout, "> ") {
+		if !r.handle(strings.TrimSpace(scanner.Text())) {
+			return 0
+		}
+	}
+	fmt.Fprintln(stdout)
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// Handle a line typed by the user, and return false to exit.
+func (r *repl) handle(line string) bool {
+	command, arg, _ := strings.Cut(line, " ")
+	switch command {
+	case "":
+	case ":quit", ":q", ":exit":
+		return false
+	case ":help":
+		fmt.Fprint(r.out, replHelp)
+	case ":stack":
+		r.printStack()
+	case ":mem":
+		r.printMemory()
+	case ":reset":
+		r.reset()
+		r.printState(0)
+	case ":undo":
+		if len(r.entries) == 0 {
+			fmt.Fprintln(r.out, "nothing to undo")
+			return true
+		}
+		r.entries = r.entries[:len(r.entries)-1]
+		r.replay(nil)
+		r.printState(0)
+	case ":load":
+		if arg = strings.TrimSpace(arg); arg == "" {
+			fmt.Fprintln(r.out, "usage: :load <file>")
+			return true
+		}
+		code, err := readCode(arg)
+		if err != nil {
+			fmt.Fprintln(r.out, "error:", err)
+			return true
+		}
+		r.execute(code)
+	default:
+		if strings.HasPrefix(command, ":") {
+			fmt.Fprintf(r.out, "unknown command %q, type :help for the list of commands\n", command)
+			return true
+		}
+		code, err := asm.Assemble(line)
+		if err != nil {
+			fmt.Fprintln(r.out, "error:", err)
+			return true
+		}
+		r.execute(code)
+	}
+	return true
+}
+
+// Execute the code of a new entry, keeping it only if it executes without error.
+func (r *repl) execute(code []byte) {
+	if len(code) == 0 {
+		return
+	}
+	if r.halted != nil {
+		fmt.Fprintln(r.out, "error: the execution halted, type :undo or :reset to continue")
+		return
+	}
+	gas := r.evm.Gas()
+	if err := r.replay(code); err != nil {
+		fmt.Fprintln(r.out, "error:", err)
+		r.replay(nil)
+		return
+	}
+	r.entries = append(r.entries, code)
+	r.printState(gas - r.evm.Gas())
+}
+
+// Rebuild the state by executing the code of the accepted entries, followed by the given code.
+func (r *repl) replay(code []byte) error {
+	var all []byte
+	for _, entry := range r.entries {
+		all = append(all, entry...)
+	}
+	all = append(all, code...)
+
+	r.evm = evm.NewEVM(all, r.opts...)
+	r.halted = nil
+	for r.evm.PC() < len(all) {
+		step := r.evm.Step()
+		if step.Err != nil {
+			return step.Err
+		}
+		if step.Halted {
+			r.halted = step.Result
+			break
+		}
+	}
+	return nil
+}
+
+func (r *repl) reset() {
+	r.entries = nil
+	r.replay(nil)
+}
+
+// Print the stack, the memory and the gas, with the gas consumed by the last entry.
+func (r *repl) printState(cost uint64) {
+	r.printStack()
+	r.printMemory()
+	fmt.Fprintf(r.out, "gas: %d used, %d left", r.gas-r.evm.Gas(), r.evm.Gas())
+	if cost > 0 {
+		fmt.Fprintf(r.out, ", last cost %d", cost)
+	}
+	fmt.Fprintln(r.out)
+	if r.halted != nil {
+		fmt.Fprintf(r.out, "halted, output: %s\n", hexutil.Encode(r.halted.ReturnData))
+	}
+}
+
+func (r *repl) printStack() {
+	items := r.evm.StackItems()
+	if len(items) == 0 {
+		fmt.Fprintln(r.out, "stack: empty")
+		return
+	}
+	fmt.Fprintln(r.out, "stack, from the top:")
+	for i := len(items) - 1; i >= 0; i-- {
+		fmt.Fprintf(r.out, "  %d: %s\n", len(items)-1-i, items[i].Hex())
+	}
+}
+
+// Print the memory as a hexdump of 32-byte words.
+func (r *repl) printMemory() {
+	memory := r.evm.MemoryBytes()
+	if len(memory) == 0 {
+		fmt.Fprintln(r.out, "memory: empty")
+		return
+	}
+	fmt.Fprintln(r.out, "memory:")
+	for offset := 0; offset < len(memory); offset += 32 {
+		fmt.Fprintf(r.out, "  %04x: %x\n", offset, memory[offset:min(offset+32, len(memory))])
+	}
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"go-evm/evm"
+	"go-evm/srcmap"
+	"go-evm/tracers"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/holiman/uint256"
+)
+
+func init() {
+	commands = append(commands, command{
+		name:        "run",
+		usage:       "run [flags]",
+		description: "Execute bytecode and print its result",
+		run:         runCodeCommand,
+	})
+}
+
+// runOutput is the result of an execution printed by the run command with --json.
+// The stack is listed from the bottom to the top.
+type runOutput struct {
+	Output       hexutil.Bytes `json:"output"`
+	GasUsed      uint64        `json:"gasUsed"`
+	GasRefunded  uint64        `json:"gasRefunded"`
+	Stack        []string      `json:"stack"`
+	Logs         []runLog      `json:"logs"`
+	Error        string        `json:"error,omitempty"`
+	RevertReason string        `json:"revertReason,omitempty"`
+}
+
+type runLog struct {
+	Address common.Address `json:"address"`
+	Topics  []common.Hash  `json:"topics"`
+	Data    hexutil.Bytes  `json:"data"`
+}
+
+func runCodeCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	code := flags.String("code", "", "bytecode to execute, in hex")
+	codeFile := flags.String("codefile", "", "file containing the bytecode to execute, in hex or raw bytes")
+	input := flags.String("input", "", "input data of the call, in hex")
+	value := flags.String("value", "0", "value passed to the call, in wei, in decimal or 0x-prefixed hex")
+	gas := flags.Uint64("gas", evm.DefaultGasLimit, "gas available to the execution")
+	sender := flags.String("sender", "", "address of the caller")
+	fork := flags.String("fork", evm.LatestFork.String(), "network upgrade whose rules apply")
+	jsonOutput := flags.Bool("json", false, "print the result as JSON")
+	tracerName := flags.String("tracer", "", "trace the execution with the call, json or prestate tracer, writing the trace to the standard error; the call trace only has the top-level call, as there are no CALL opcodes, and the prestate trace only has the code and the storage accessed by custom operations and precompiles, without balances or nonces, so it is not go-ethereum compatible")
+	withLogs := flags.Bool("with-logs", false, "include the events in the trace of the call tracer")
+	sourceMapFlag := flags.String("srcmap", "", "solc standard JSON output and contract of the code, as <output.json>:<file>:<name>, to print the source locations in the error and the json trace")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 || (*code == "") == (*codeFile == "") {
+		fmt.Fprintln(stderr, "Usage: tiny-gevm run (--code <hex> | --codefile <file>) [flags]")
+		flags.PrintDefaults()
+		return 2
+	}
+
+	opts, err := runOptions(*input, *value, *gas, *sender, *fork)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	var bytecode []byte
+	if *codeFile != "" {
+		bytecode, err = readCode(*codeFile)
+	} else {
+		bytecode, err = decodeHex(*code)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "invalid code: %v\n", err)
+		return 2
+	}
+
+	var sourceMap *srcmap.SourceMap
+	if *sourceMapFlag != "" {
+		if sourceMap, err = loadSourceMap(*sourceMapFlag, bytecode); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+	}
+	tracer, writeTrace, err := newRunTracer(*tracerName, *withLogs, bytecode, sourceMap, stderr)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	if tracer != nil {
+		opts = append(opts, evm.WithTracer(tracer))
+	}
+
+	result := evm.NewEVM(bytecode, opts...).Run()
+	if writeTrace != nil {
+		if err := writeTrace(); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+	if *jsonOutput {
+		err = writeResultJSON(stdout, result, sourceMap)
+	} else {
+		err = writeResult(stdout, result, sourceMap)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if result.Failed() {
+		return 1
+	}
+	return 0
+}
+
+// Build the options of the EVM from the flags.
+func runOptions(input, value string, gas uint64, sender, fork string) ([]evm.Option, error) {
+	opts := []evm.Option{evm.WithGas(gas)}
+	if input != "" {
+		data, err := decodeHex(input)
+		if err != nil {
+			return nil, fmt.Errorf("invalid input: %w", err)
+		}
+		opts = append(opts, evm.WithInput(data))
+	}
+	v, err := parseValue(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value: %w", err)
+	}
+	opts = append(opts, evm.WithValue(v))
+	if sender != "" {
+		if !common.IsHexAddress(sender) {
+			return nil, fmt.Errorf("invalid sender: %q", sender)
+		}
+		opts = append(opts, evm.WithCaller(common.HexToAddress(sender)))
+	}
+	f, err := evm.ParseFork(fork)
+	if err != nil {
+		return nil, err
+	}
+	return append(opts, evm.WithFork(f)), nil
+}
+
+// Load the source map of the code from a solc standard JSON output, given as <output.json>:<file>:<name>.
+// The source map of the creation code is used if the code is the creation code of the contract, and the one of the runtime code otherwise.
+func loadSourceMap(spec string, code []byte) (*srcmap.SourceMap, error) {
+	path, contractName, ok := strings.Cut(spec, ":")
+	if !ok || !strings.Contains(contractName, ":") {
+		return nil, fmt.Errorf("invalid source map %q, expected <output.json>:<file>:<name>", spec)
+	}
+	contract, err := srcmap.LoadStandardJSON(path, contractName)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(code, contract.Code) {
+		return contract.SourceMap, nil
+	}
+	return contract.RuntimeSourceMap, nil
+}
+
+// Create the tracer named by the --tracer flag, and the function writing its result once the execution halted.
+// The json tracer writes each step while the code executes, so it has no result to write.
+// It adds the source location of each step if the code has a source map.
+func newRunTracer(name string, withLogs bool, code []byte, sourceMap *srcmap.SourceMap, w io.Writer) (evm.ITracer, func() error, error) {
+	if withLogs && name != "call" {
+		return nil, nil, fmt.Errorf("--with-logs requires --tracer call")
+	}
+	switch name {
+	case "":
+		return nil, nil, nil
+	case "json":
+		var opts []tracers.JSONTracerOption
+		if sourceMap != nil {
+			opts = append(opts, tracers.WithSourceMap(code, sourceMap))
+		}
+		return tracers.NewJSONTracer(w, opts...), nil, nil
+	case "call":
+		var opts []tracers.CallTracerOption
+		if withLogs {
+			opts = append(opts, tracers.WithLogs())
+		}
+		tracer := tracers.NewCallTracer(opts...)
+		return tracer, func() error { return writeTrace(w, tracer.JSON) }, nil
+	case "prestate":
+		tracer := tracers.NewPrestateTracer()
+		return tracer, func() error { return writeTrace(w, tracer.JSON) }, nil
+	}
+	return nil, nil, fmt.Errorf("unknown tracer %q, expected call, json or prestate", name)
+}
+
+// Write the JSON result of a tracer on its own line.
+func writeTrace(w io.Writer, result func() (json.RawMessage, error)) error {
+	trace, err := result()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", trace)
+	return err
+}
+
+// Parse a value in decimal, or in hex if it has the 0x prefix.
+func parseValue(s string) (*uint256.Int, error) {
+	if len(s) > 1 && (s[:2] == "0x" || s[:2] == "0X") {
+		// FromHex rejects leading zeros, like 0x01.
+		if digits := strings.TrimLeft(s[2:], "0"); digits != "" || len(s) == 2 {
+			return uint256.FromHex("0x" + digits)
+		}
+		return new(uint256.Int), nil
+	}
+	return uint256.FromDecimal(s)
+}
+
+// Describe the error which halted the execution, with the source location of the failing instruction if the code has a source map.
+func describeError(err error, sourceMap *srcmap.SourceMap) string {
+	if sourceMap != nil {
+		return sourceMap.Describe(err)
+	}
+	return err.Error()
+}
+
+// Write the result of the execution in a human-readable form.
+func writeResult(w io.Writer, result *evm.ExecutionResult, sourceMap *srcmap.SourceMap) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "output: %s\n", hexutil.Encode(result.ReturnData))
+	fmt.Fprintf(&b, "gas used: %d\n", result.GasUsed)
+	if result.GasRefunded > 0 {
+		fmt.Fprintf(&b, "gas refunded: %d\n", result.GasRefunded)
+	}
+
+	fmt.Fprintln(&b, "stack, from the top:")
+	for i := len(result.Stack) - 1; i >= 0; i-- {
+		fmt.Fprintf(&b, "  %d: %s\n", len(result.Stack)-1-i, result.Stack[i].Hex())
+	}
+
+	if len(result.Logs) > 0 {
+		fmt.Fprintln(&b, "logs:")
+		for i, log := range result.Logs {
+			fmt.Fprintf(&b, "  %d: address %s\n", i, log.Address.Hex())
+			for j, topic := range log.Topics {
+				fmt.Fprintf(&b, "     topic %d: %s\n", j, topic.Hex())
+			}
+			fmt.Fprintf(&b, "     data: %s\n", hexutil.Encode(log.Data))
+		}
+	}
+
+	if result.Err != nil {
+		fmt.Fprintf(&b, "error: %s\n", describeError(result.Err, sourceMap))
+		if result.RevertReason != "" {
+			fmt.Fprintf(&b, "revert reason: %s\n", result.RevertReason)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Write the result of the execution as indented JSON.
+func writeResultJSON(w io.Writer, result *evm.ExecutionResult, sourceMap *srcmap.SourceMap) error {
+	output := runOutput{
+		Output:       result.ReturnData,
+		GasUsed:      result.GasUsed,
+		GasRefunded:  result.GasRefunded,
+		Stack:        make([]string, len(result.Stack)),
+		Logs:         make([]runLog, len(result.Logs)),
+		RevertReason: result.RevertReason,
+	}
+	if output.Output == nil {
+		output.Output = []byte{}
+	}
+	for i, item := range result.Stack {
+		output.Stack[i] = item.Hex()
+	}
+	for i, log := range result.Logs {
+		output.Logs[i] = runLog{Address: log.Address, Topics: log.Topics, Data: log.Data}
+	}
+	if result.Err != nil {
+		output.Error = describeError(result.Err, sourceMap)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(output)
+}
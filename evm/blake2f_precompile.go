@@ -0,0 +1,69 @@
+package evm
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/crypto/blake2b"
+)
+
+var (
+	// ErrBlake2FInvalidInputLength is returned when the BLAKE2F input is not exactly 213 bytes long.
+	ErrBlake2FInvalidInputLength = errors.New("invalid input length")
+	// ErrBlake2FInvalidFinalFlag is returned when the BLAKE2F final block indicator is neither 0 nor 1.
+	ErrBlake2FInvalidFinalFlag = errors.New("invalid final flag")
+)
+
+// Size of the BLAKE2F input, in bytes.
+const blake2FInputSize = 213
+
+// blake2F implements the BLAKE2 compression function F precompile (0x09), as defined in EIP-152.
+// Input: [rounds (4 bytes, big-endian), h (64 bytes), m (128 bytes), t (16 bytes), f (1 byte)]
+// The state vector h, the message block m and the offset counters t are encoded as little-endian 8-byte words.
+// The final block indicator flag f must be either 0 or 1.
+// Output: the updated state vector h (64 bytes).
+type blake2F struct{}
+
+// The gas cost is equal to the number of rounds.
+// If the input is malformed, the cost is zero and the execution fails.
+func (c *blake2F) RequiredGas(input []byte) uint64 {
+	if len(input) != blake2FInputSize {
+		return 0
+	}
+	return uint64(binary.BigEndian.Uint32(input[0:4]))
+}
+
+func (c *blake2F) Run(input []byte) ([]byte, error) {
+	if len(input) != blake2FInputSize {
+		return nil, ErrBlake2FInvalidInputLength
+	}
+	if input[212] > 1 {
+		return nil, ErrBlake2FInvalidFinalFlag
+	}
+
+	// Decode the parameters of the compression function.
+	rounds := binary.BigEndian.Uint32(input[0:4])
+	var h [8]uint64
+	for i := range h {
+		offset := 4 + i*8
+		h[i] = binary.LittleEndian.Uint64(input[offset : offset+8])
+	}
+	var m [16]uint64
+	for i := range m {
+		offset := 68 + i*8
+		m[i] = binary.LittleEndian.Uint64(input[offset : offset+8])
+	}
+	t := [2]uint64{
+		binary.LittleEndian.Uint64(input[196:204]),
+		binary.LittleEndian.Uint64(input[204:212]),
+	}
+	final := input[212] == 1
+
+	// Compress the block and encode the updated state vector.
+	blake2b.F(&h, m, t, final, rounds)
+	output := make([]byte, 64)
+	for i := range h {
+		binary.LittleEndian.PutUint64(output[i*8:(i+1)*8], h[i])
+	}
+	return output, nil
+}
@@ -0,0 +1,32 @@
+package evm
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestBlake2F(t *testing.T) {
+	testPrecompileVectors(t, &blake2F{}, "blake2F")
+}
+
+func TestBlake2FFailures(t *testing.T) {
+	testPrecompileFailureVectors(t, &blake2F{}, "blake2f")
+}
+
+func TestBlake2FActivation(t *testing.T) {
+	address := common.BytesToAddress([]byte{0x09})
+	if _, ok := PrecompiledContracts(Byzantium)[address]; ok {
+		t.Errorf("BLAKE2F should not be active before Istanbul")
+	}
+	if _, ok := PrecompiledContracts(Istanbul)[address]; !ok {
+		t.Errorf("BLAKE2F should be active from Istanbul")
+	}
+}
+
+func TestBlake2FMalformedInputGas(t *testing.T) {
+	p := &blake2F{}
+	if gas := p.RequiredGas(make([]byte, blake2FInputSize-1)); gas != 0 {
+		t.Errorf("RequiredGas() returned %d for a malformed input, wanted 0", gas)
+	}
+}
@@ -0,0 +1,38 @@
+package evm
+
+import (
+	"github.com/holiman/uint256"
+)
+
+// IBlockOps defines operations that read information about the current block and transaction.
+type IBlockOps interface {
+	// BlobHash reads the versioned hash of a blob carried by the transaction.
+	// It pops an item from the stack, this is the index of the blob.
+	// Then it pushes the versioned hash of the blob to the top of the stack.
+	// If the index is out of range, it pushes zero.
+	// Stack: [index, ...] -> [blobHash, ...]
+	BlobHash() error
+
+	// BlobBaseFee pushes the blob base fee of the current block to the top of the stack.
+	// Stack: [...] -> [blobBaseFee, ...]
+	BlobBaseFee() error
+}
+
+func (e *EVM) BlobHash() error {
+	// Load index from the stack.
+	index, err := e.stack.Pop()
+	if err != nil {
+		return err
+	}
+
+	// Push the versioned hash of the blob, or zero if the index is out of range.
+	value := new(uint256.Int)
+	if index.IsUint64() && index.Uint64() < uint64(len(e.env.blobHashes)) {
+		value.SetBytes(e.env.blobHashes[index.Uint64()][:])
+	}
+	return e.stack.Push(value)
+}
+
+func (e *EVM) BlobBaseFee() error {
+	return e.stack.Push(new(uint256.Int).Set(e.env.blobBaseFee))
+}
@@ -0,0 +1,73 @@
+package evm
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+)
+
+func TestBlobHash(t *testing.T) {
+	op := func(evm IEVM) error { return evm.BlobHash() }
+	hashes := []common.Hash{
+		common.BytesToHash([]byte{0x01, 0x11}),
+		common.BytesToHash([]byte{0x01, 0x22}),
+	}
+
+	tests := []struct {
+		name     string
+		index    uint64
+		expected uint64
+	}{
+		{"first blob", 0, 0x0111},
+		{"second blob", 1, 0x0122},
+		{"index out of range", 2, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			evm := NewEVM(nil, WithBlobHashes(hashes...))
+			initialStack := []uint64{1, test.index}
+			expectedStack := []uint64{1, test.expected}
+			testStackOperationWithExistingEVM(t, evm, op, nil, initialStack, expectedStack, nil, nil)
+		})
+	}
+}
+
+func TestBlobHashWithLargeIndex(t *testing.T) {
+	evm := NewEVM(nil, WithBlobHashes(common.BytesToHash([]byte{0x01})))
+	testEvm, ok := evm.(ExtendedEVM)
+	if !ok {
+		t.Fatal("IEVM does not implement internalEVM")
+	}
+
+	// Push an index that doesn't fit in 64 bits.
+	index := new(uint256.Int).Lsh(uint256.NewInt(1), 64)
+	if err := testEvm.HelperPush(index); err != nil {
+		t.Fatalf("Push() returned an unexpected error: %v", err)
+	}
+	if err := evm.BlobHash(); err != nil {
+		t.Fatalf("Operation returned an unexpected error: %v", err)
+	}
+
+	value, err := testEvm.HelperPop()
+	if err != nil {
+		t.Fatalf("Pop() returned an unexpected error: %v", err)
+	}
+	if !value.IsZero() {
+		t.Errorf("Expected 0, got %v", value)
+	}
+}
+
+func TestBlobHashOnEmptyStack(t *testing.T) {
+	op := func(evm IEVM) error { return evm.BlobHash() }
+	testStackOperationWithNewEVM(t, op, ErrStackUnderflow, nil, nil, nil, nil, nil)
+}
+
+func TestBlobBaseFee(t *testing.T) {
+	op := func(evm IEVM) error { return evm.BlobBaseFee() }
+	evm := NewEVM(nil, WithBlobBaseFee(uint256.NewInt(7)))
+	testStackOperationWithExistingEVM(t, evm, op, nil, []uint64{1}, []uint64{1, 7}, nil, nil)
+
+	// The blob base fee defaults to zero.
+	testStackOperationWithNewEVM(t, op, nil, []uint64{1}, []uint64{1, 0}, nil, nil, nil)
+}
@@ -0,0 +1,359 @@
+package evm
+
+import (
+	"errors"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fp"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+)
+
+var (
+	// ErrBLS12381InvalidInputLength is returned when the input length doesn't match the expected encoding.
+	ErrBLS12381InvalidInputLength = errors.New("invalid input length")
+	// ErrBLS12381InvalidFieldElementTopBytes is returned when the 16 top bytes of a field element are not zero.
+	ErrBLS12381InvalidFieldElementTopBytes = errors.New("invalid field element top bytes")
+	// ErrBLS12381PointNotOnCurve is returned when a point is not on the curve.
+	ErrBLS12381PointNotOnCurve = errors.New("invalid point: not on curve")
+	// ErrBLS12381G1PointSubgroup is returned when a G1 point is not in the correct subgroup.
+	ErrBLS12381G1PointSubgroup = errors.New("g1 point is not on correct subgroup")
+	// ErrBLS12381G2PointSubgroup is returned when a G2 point is not in the correct subgroup.
+	ErrBLS12381G2PointSubgroup = errors.New("g2 point is not on correct subgroup")
+)
+
+// Gas costs of the BLS12-381 precompiles, as defined in EIP-2537.
+const (
+	bls12381G1AddGas          uint64 = 375
+	bls12381G1MulGas          uint64 = 12000
+	bls12381G2AddGas          uint64 = 600
+	bls12381G2MulGas          uint64 = 22500
+	bls12381PairingBaseGas    uint64 = 37700
+	bls12381PairingPerPairGas uint64 = 32600
+	bls12381MapG1Gas          uint64 = 5500
+	bls12381MapG2Gas          uint64 = 23800
+)
+
+// Sizes of the BLS12-381 encodings, in bytes.
+// Field elements are encoded on 64 bytes, the 16 top bytes being zero.
+const (
+	bls12381FieldElementSize = 64
+	bls12381G1PointSize      = 2 * bls12381FieldElementSize
+	bls12381G2PointSize      = 4 * bls12381FieldElementSize
+	bls12381ScalarSize       = 32
+	bls12381G1MSMPairSize    = bls12381G1PointSize + bls12381ScalarSize
+	bls12381G2MSMPairSize    = bls12381G2PointSize + bls12381ScalarSize
+	bls12381PairingPairSize  = bls12381G1PointSize + bls12381G2PointSize
+)
+
+// Discount tables of the MSM precompiles, indexed by the number of pairs minus one.
+// The last value applies to any larger number of pairs.
+var (
+	bls12381G1MSMDiscountTable = [128]uint64{1000, 949, 848, 797, 764, 750, 738, 728, 719, 712, 705, 698, 692, 687, 682, 677, 673, 669, 665, 661, 658, 654, 651, 648, 645, 642, 640, 637, 635, 632, 630, 627, 625, 623, 621, 619, 617, 615, 613, 611, 609, 608, 606, 604, 603, 601, 599, 598, 596, 595, 593, 592, 591, 589, 588, 586, 585, 584, 582, 581, 580, 579, 577, 576, 575, 574, 573, 572, 570, 569, 568, 567, 566, 565, 564, 563, 562, 561, 560, 559, 558, 557, 556, 555, 554, 553, 552, 551, 550, 549, 548, 547, 547, 546, 545, 544, 543, 542, 541, 540, 540, 539, 538, 537, 536, 536, 535, 534, 533, 532, 532, 531, 530, 529, 528, 528, 527, 526, 525, 525, 524, 523, 522, 522, 521, 520, 520, 519}
+	bls12381G2MSMDiscountTable = [128]uint64{1000, 1000, 923, 884, 855, 832, 812, 796, 782, 770, 759, 749, 740, 732, 724, 717, 711, 704, 699, 693, 688, 683, 679, 674, 670, 666, 663, 659, 655, 652, 649, 646, 643, 640, 637, 634, 632, 629, 627, 624, 622, 620, 618, 615, 613, 611, 609, 607, 606, 604, 602, 600, 598, 597, 595, 593, 592, 590, 589, 587, 586, 584, 583, 582, 580, 579, 578, 576, 575, 574, 573, 571, 570, 569, 568, 567, 566, 565, 563, 562, 561, 560, 559, 558, 557, 556, 555, 554, 553, 552, 552, 551, 550, 549, 548, 547, 546, 545, 545, 544, 543, 542, 541, 541, 540, 539, 538, 537, 537, 536, 535, 535, 534, 533, 532, 532, 531, 530, 530, 529, 528, 528, 527, 526, 526, 525, 524, 524}
+)
+
+// bls12381G1Add implements the BLS12_G1ADD precompile (0x0b).
+// Input: two G1 points (128 bytes each).
+// Output: the sum of the two points (128 bytes).
+// The points are not subgroup-checked.
+type bls12381G1Add struct{}
+
+func (c *bls12381G1Add) RequiredGas(input []byte) uint64 {
+	return bls12381G1AddGas
+}
+
+func (c *bls12381G1Add) Run(input []byte) ([]byte, error) {
+	if len(input) != 2*bls12381G1PointSize {
+		return nil, ErrBLS12381InvalidInputLength
+	}
+	p1, err := decodeBLS12381G1Point(input[:bls12381G1PointSize])
+	if err != nil {
+		return nil, err
+	}
+	p2, err := decodeBLS12381G1Point(input[bls12381G1PointSize:])
+	if err != nil {
+		return nil, err
+	}
+	return encodeBLS12381G1Point(new(bls12381.G1Affine).Add(p1, p2)), nil
+}
+
+// bls12381G1MSM implements the BLS12_G1MSM precompile (0x0c).
+// Input: k * [G1 point (128 bytes), scalar (32 bytes)]
+// Output: the multi-scalar multiplication of the points (128 bytes).
+type bls12381G1MSM struct{}
+
+func (c *bls12381G1MSM) RequiredGas(input []byte) uint64 {
+	return bls12381MSMGas(len(input)/bls12381G1MSMPairSize, bls12381G1MulGas, &bls12381G1MSMDiscountTable)
+}
+
+func (c *bls12381G1MSM) Run(input []byte) ([]byte, error) {
+	if len(input) == 0 || len(input)%bls12381G1MSMPairSize != 0 {
+		return nil, ErrBLS12381InvalidInputLength
+	}
+
+	k := len(input) / bls12381G1MSMPairSize
+	points := make([]bls12381.G1Affine, k)
+	scalars := make([]fr.Element, k)
+	for i := 0; i < k; i++ {
+		offset := i * bls12381G1MSMPairSize
+		p, err := decodeBLS12381G1Point(input[offset : offset+bls12381G1PointSize])
+		if err != nil {
+			return nil, err
+		}
+		if !p.IsInSubGroup() {
+			return nil, ErrBLS12381G1PointSubgroup
+		}
+		points[i] = *p
+		scalars[i].SetBytes(input[offset+bls12381G1PointSize : offset+bls12381G1MSMPairSize])
+	}
+
+	result := new(bls12381.G1Affine)
+	if _, err := result.MultiExp(points, scalars, ecc.MultiExpConfig{}); err != nil {
+		return nil, err
+	}
+	return encodeBLS12381G1Point(result), nil
+}
+
+// bls12381G2Add implements the BLS12_G2ADD precompile (0x0d).
+// Input: two G2 points (256 bytes each).
+// Output: the sum of the two points (256 bytes).
+// The points are not subgroup-checked.
+type bls12381G2Add struct{}
+
+func (c *bls12381G2Add) RequiredGas(input []byte) uint64 {
+	return bls12381G2AddGas
+}
+
+func (c *bls12381G2Add) Run(input []byte) ([]byte, error) {
+	if len(input) != 2*bls12381G2PointSize {
+		return nil, ErrBLS12381InvalidInputLength
+	}
+	p1, err := decodeBLS12381G2Point(input[:bls12381G2PointSize])
+	if err != nil {
+		return nil, err
+	}
+	p2, err := decodeBLS12381G2Point(input[bls12381G2PointSize:])
+	if err != nil {
+		return nil, err
+	}
+	return encodeBLS12381G2Point(new(bls12381.G2Affine).Add(p1, p2)), nil
+}
+
+// bls12381G2MSM implements the BLS12_G2MSM precompile (0x0e).
+// Input: k * [G2 point (256 bytes), scalar (32 bytes)]
+// Output: the multi-scalar multiplication of the points (256 bytes).
+type bls12381G2MSM struct{}
+
+func (c *bls12381G2MSM) RequiredGas(input []byte) uint64 {
+	return bls12381MSMGas(len(input)/bls12381G2MSMPairSize, bls12381G2MulGas, &bls12381G2MSMDiscountTable)
+}
+
+func (c *bls12381G2MSM) Run(input []byte) ([]byte, error) {
+	if len(input) == 0 || len(input)%bls12381G2MSMPairSize != 0 {
+		return nil, ErrBLS12381InvalidInputLength
+	}
+
+	k := len(input) / bls12381G2MSMPairSize
+	points := make([]bls12381.G2Affine, k)
+	scalars := make([]fr.Element, k)
+	for i := 0; i < k; i++ {
+		offset := i * bls12381G2MSMPairSize
+		p, err := decodeBLS12381G2Point(input[offset : offset+bls12381G2PointSize])
+		if err != nil {
+			return nil, err
+		}
+		if !p.IsInSubGroup() {
+			return nil, ErrBLS12381G2PointSubgroup
+		}
+		points[i] = *p
+		scalars[i].SetBytes(input[offset+bls12381G2PointSize : offset+bls12381G2MSMPairSize])
+	}
+
+	result := new(bls12381.G2Affine)
+	if _, err := result.MultiExp(points, scalars, ecc.MultiExpConfig{}); err != nil {
+		return nil, err
+	}
+	return encodeBLS12381G2Point(result), nil
+}
+
+// bls12381Pairing implements the BLS12_PAIRING_CHECK precompile (0x0f).
+// Input: k * [G1 point (128 bytes), G2 point (256 bytes)]
+// Output: 1 if the product of the pairings is equal to one, 0 otherwise (32 bytes).
+type bls12381Pairing struct{}
+
+func (c *bls12381Pairing) RequiredGas(input []byte) uint64 {
+	return bls12381PairingBaseGas + uint64(len(input)/bls12381PairingPairSize)*bls12381PairingPerPairGas
+}
+
+func (c *bls12381Pairing) Run(input []byte) ([]byte, error) {
+	if len(input) == 0 || len(input)%bls12381PairingPairSize != 0 {
+		return nil, ErrBLS12381InvalidInputLength
+	}
+
+	k := len(input) / bls12381PairingPairSize
+	g1Points := make([]bls12381.G1Affine, k)
+	g2Points := make([]bls12381.G2Affine, k)
+	for i := 0; i < k; i++ {
+		offset := i * bls12381PairingPairSize
+		g1, err := decodeBLS12381G1Point(input[offset : offset+bls12381G1PointSize])
+		if err != nil {
+			return nil, err
+		}
+		g2, err := decodeBLS12381G2Point(input[offset+bls12381G1PointSize : offset+bls12381PairingPairSize])
+		if err != nil {
+			return nil, err
+		}
+		if !g1.IsInSubGroup() {
+			return nil, ErrBLS12381G1PointSubgroup
+		}
+		if !g2.IsInSubGroup() {
+			return nil, ErrBLS12381G2PointSubgroup
+		}
+		g1Points[i] = *g1
+		g2Points[i] = *g2
+	}
+
+	result := make([]byte, 32)
+	if ok, err := bls12381.PairingCheck(g1Points, g2Points); err == nil && ok {
+		result[31] = 1
+	}
+	return result, nil
+}
+
+// bls12381MapG1 implements the BLS12_MAP_FP_TO_G1 precompile (0x10).
+// Input: a base field element (64 bytes).
+// Output: the G1 point the element maps to (128 bytes).
+type bls12381MapG1 struct{}
+
+func (c *bls12381MapG1) RequiredGas(input []byte) uint64 {
+	return bls12381MapG1Gas
+}
+
+func (c *bls12381MapG1) Run(input []byte) ([]byte, error) {
+	if len(input) != bls12381FieldElementSize {
+		return nil, ErrBLS12381InvalidInputLength
+	}
+	element, err := decodeBLS12381FieldElement(input)
+	if err != nil {
+		return nil, err
+	}
+	point := bls12381.MapToG1(element)
+	return encodeBLS12381G1Point(&point), nil
+}
+
+// bls12381MapG2 implements the BLS12_MAP_FP2_TO_G2 precompile (0x11).
+// Input: an element of the quadratic extension field (128 bytes).
+// Output: the G2 point the element maps to (256 bytes).
+type bls12381MapG2 struct{}
+
+func (c *bls12381MapG2) RequiredGas(input []byte) uint64 {
+	return bls12381MapG2Gas
+}
+
+func (c *bls12381MapG2) Run(input []byte) ([]byte, error) {
+	if len(input) != 2*bls12381FieldElementSize {
+		return nil, ErrBLS12381InvalidInputLength
+	}
+	a0, err := decodeBLS12381FieldElement(input[:bls12381FieldElementSize])
+	if err != nil {
+		return nil, err
+	}
+	a1, err := decodeBLS12381FieldElement(input[bls12381FieldElementSize:])
+	if err != nil {
+		return nil, err
+	}
+	point := bls12381.MapToG2(bls12381.E2{A0: a0, A1: a1})
+	return encodeBLS12381G2Point(&point), nil
+}
+
+// Compute the gas cost of a multi-scalar multiplication of k pairs.
+// gas = k * mulGas * discount(k) / 1000
+func bls12381MSMGas(k int, mulGas uint64, discountTable *[128]uint64) uint64 {
+	if k == 0 {
+		return 0
+	}
+	discount := discountTable[len(discountTable)-1]
+	if k <= len(discountTable) {
+		discount = discountTable[k-1]
+	}
+	return uint64(k) * mulGas * discount / 1000
+}
+
+// Decode a base field element encoded on 64 bytes.
+// It returns an error if the 16 top bytes are not zero or if the element is not lower than the field modulus.
+func decodeBLS12381FieldElement(data []byte) (fp.Element, error) {
+	for _, b := range data[:16] {
+		if b != 0 {
+			return fp.Element{}, ErrBLS12381InvalidFieldElementTopBytes
+		}
+	}
+	var buf [fp.Bytes]byte
+	copy(buf[:], data[16:])
+	return fp.BigEndian.Element(&buf)
+}
+
+// Decode a G1 point encoded on 128 bytes.
+// The point at infinity is encoded as zeros.
+// It returns an error if the point is not on the curve.
+func decodeBLS12381G1Point(data []byte) (*bls12381.G1Affine, error) {
+	x, err := decodeBLS12381FieldElement(data[:bls12381FieldElementSize])
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeBLS12381FieldElement(data[bls12381FieldElementSize:])
+	if err != nil {
+		return nil, err
+	}
+	p := &bls12381.G1Affine{X: x, Y: y}
+	if !p.IsOnCurve() {
+		return nil, ErrBLS12381PointNotOnCurve
+	}
+	return p, nil
+}
+
+// Decode a G2 point encoded on 256 bytes.
+// The point at infinity is encoded as zeros.
+// It returns an error if the point is not on the curve.
+func decodeBLS12381G2Point(data []byte) (*bls12381.G2Affine, error) {
+	var coordinates [4]fp.Element
+	for i := range coordinates {
+		var err error
+		coordinates[i], err = decodeBLS12381FieldElement(data[i*bls12381FieldElementSize : (i+1)*bls12381FieldElementSize])
+		if err != nil {
+			return nil, err
+		}
+	}
+	p := &bls12381.G2Affine{
+		X: bls12381.E2{A0: coordinates[0], A1: coordinates[1]},
+		Y: bls12381.E2{A0: coordinates[2], A1: coordinates[3]},
+	}
+	if !p.IsOnCurve() {
+		return nil, ErrBLS12381PointNotOnCurve
+	}
+	return p, nil
+}
+
+// Encode a G1 point on 128 bytes.
+func encodeBLS12381G1Point(p *bls12381.G1Affine) []byte {
+	output := make([]byte, bls12381G1PointSize)
+	encodeBLS12381FieldElement(output[:bls12381FieldElementSize], &p.X)
+	encodeBLS12381FieldElement(output[bls12381FieldElementSize:], &p.Y)
+	return output
+}
+
+// Encode a G2 point on 256 bytes.
+func encodeBLS12381G2Point(p *bls12381.G2Affine) []byte {
+	output := make([]byte, bls12381G2PointSize)
+	coordinates := []*fp.Element{&p.X.A0, &p.X.A1, &p.Y.A0, &p.Y.A1}
+	for i, coordinate := range coordinates {
+		encodeBLS12381FieldElement(output[i*bls12381FieldElementSize:(i+1)*bls12381FieldElementSize], coordinate)
+	}
+	return output
+}
+
+// Encode a base field element on 64 bytes, the 16 top bytes being zero.
+func encodeBLS12381FieldElement(output []byte, element *fp.Element) {
+	bytes := element.Bytes()
+	copy(output[16:], bytes[:])
+}
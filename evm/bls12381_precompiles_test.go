@@ -0,0 +1,69 @@
+package evm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestBLS12381Precompiles(t *testing.T) {
+	tests := []struct {
+		address byte
+		vectors []string
+	}{
+		{0x0b, []string{"blsG1Add"}},
+		{0x0c, []string{"blsG1Mul", "blsG1MultiExp"}},
+		{0x0d, []string{"blsG2Add"}},
+		{0x0e, []string{"blsG2Mul", "blsG2MultiExp"}},
+		{0x0f, []string{"blsPairing"}},
+		{0x10, []string{"blsMapG1"}},
+		{0x11, []string{"blsMapG2"}},
+	}
+
+	contracts := PrecompiledContracts(Prague)
+	for _, test := range tests {
+		p := contracts[common.BytesToAddress([]byte{test.address})]
+		for _, name := range test.vectors {
+			t.Run(name, func(t *testing.T) {
+				testPrecompileVectors(t, p, name)
+			})
+			t.Run(fmt.Sprintf("fail-%s", name), func(t *testing.T) {
+				testPrecompileFailureVectors(t, p, name)
+			})
+		}
+	}
+}
+
+func TestBLS12381Activation(t *testing.T) {
+	for address := byte(0x0b); address <= 0x11; address++ {
+		if _, ok := PrecompiledContracts(Cancun)[common.BytesToAddress([]byte{address})]; ok {
+			t.Errorf("BLS12-381 precompile %#x should not be active before Prague", address)
+		}
+		if _, ok := PrecompiledContracts(Prague)[common.BytesToAddress([]byte{address})]; !ok {
+			t.Errorf("BLS12-381 precompile %#x should be active from Prague", address)
+		}
+	}
+}
+
+func TestBLS12381MSMGas(t *testing.T) {
+	tests := []struct {
+		k        int
+		mulGas   uint64
+		table    *[128]uint64
+		expected uint64
+	}{
+		{0, bls12381G1MulGas, &bls12381G1MSMDiscountTable, 0},
+		{1, bls12381G1MulGas, &bls12381G1MSMDiscountTable, 12000},
+		{2, bls12381G1MulGas, &bls12381G1MSMDiscountTable, 2 * 12000 * 949 / 1000},
+		{128, bls12381G1MulGas, &bls12381G1MSMDiscountTable, 128 * 12000 * 519 / 1000},
+		{200, bls12381G1MulGas, &bls12381G1MSMDiscountTable, 200 * 12000 * 519 / 1000},
+		{2, bls12381G2MulGas, &bls12381G2MSMDiscountTable, 2 * 22500},
+		{200, bls12381G2MulGas, &bls12381G2MSMDiscountTable, 200 * 22500 * 524 / 1000},
+	}
+	for _, test := range tests {
+		if gas := bls12381MSMGas(test.k, test.mulGas, test.table); gas != test.expected {
+			t.Errorf("bls12381MSMGas(%d) returned %d, wanted %d", test.k, gas, test.expected)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package evm
+
+import (
+	"errors"
+	"math/big"
+
+	// The pure Go implementation is used on purpose so the EVM runs on any platform.
+	bn254 "github.com/ethereum/go-ethereum/crypto/bn256/google"
+)
+
+// ErrBadPairingInputSize is returned when the ecPairing input is not a multiple of 192 bytes.
+var ErrBadPairingInputSize = errors.New("bad elliptic curve pairing size")
+
+// Gas costs of the alt_bn128 precompiles.
+// Byzantium costs are defined in EIP-196 and EIP-197, Istanbul costs in EIP-1108.
+const (
+	bn254AddGasByzantium            uint64 = 500
+	bn254AddGasIstanbul             uint64 = 150
+	bn254ScalarMulGasByzantium      uint64 = 40000
+	bn254ScalarMulGasIstanbul       uint64 = 6000
+	bn254PairingBaseGasByzantium    uint64 = 100000
+	bn254PairingBaseGasIstanbul     uint64 = 45000
+	bn254PairingPerPairGasByzantium uint64 = 80000
+	bn254PairingPerPairGasIstanbul  uint64 = 34000
+	bn254PairingSize                       = 192
+	bn254G1PointSize                       = 64
+	bn254ScalarSize                        = 32
+)
+
+// bn254Add implements the ecAdd precompile (0x06).
+// It adds two points of the alt_bn128 curve.
+// Input: [x1, y1, x2, y2] (32 bytes each, right-padded with zeros if shorter)
+// Output: [x, y] (32 bytes each)
+type bn254Add struct {
+	gas uint64
+}
+
+func (c *bn254Add) RequiredGas(input []byte) uint64 {
+	return c.gas
+}
+
+func (c *bn254Add) Run(input []byte) ([]byte, error) {
+	p1, err := newBN254G1Point(getData(input, 0, bn254G1PointSize))
+	if err != nil {
+		return nil, err
+	}
+	p2, err := newBN254G1Point(getData(input, bn254G1PointSize, bn254G1PointSize))
+	if err != nil {
+		return nil, err
+	}
+	return new(bn254.G1).Add(p1, p2).Marshal(), nil
+}
+
+// bn254ScalarMul implements the ecMul precompile (0x07).
+// It multiplies a point of the alt_bn128 curve by a scalar.
+// Input: [x, y, s] (32 bytes each, right-padded with zeros if shorter)
+// Output: [x, y] (32 bytes each)
+type bn254ScalarMul struct {
+	gas uint64
+}
+
+func (c *bn254ScalarMul) RequiredGas(input []byte) uint64 {
+	return c.gas
+}
+
+func (c *bn254ScalarMul) Run(input []byte) ([]byte, error) {
+	p, err := newBN254G1Point(getData(input, 0, bn254G1PointSize))
+	if err != nil {
+		return nil, err
+	}
+	scalar := new(big.Int).SetBytes(getData(input, bn254G1PointSize, bn254ScalarSize))
+	return new(bn254.G1).ScalarMult(p, scalar).Marshal(), nil
+}
+
+// bn254Pairing implements the ecPairing precompile (0x08).
+// It checks that the product of the pairings of the given (G1, G2) pairs is equal to one.
+// Input: k * [x1, y1, x2_i, x2_r, y2_i, y2_r] (32 bytes each)
+// Output: 1 if the pairing check succeeds, 0 otherwise (32 bytes)
+type bn254Pairing struct {
+	baseGas    uint64
+	perPairGas uint64
+}
+
+func (c *bn254Pairing) RequiredGas(input []byte) uint64 {
+	return c.baseGas + uint64(len(input)/bn254PairingSize)*c.perPairGas
+}
+
+func (c *bn254Pairing) Run(input []byte) ([]byte, error) {
+	if len(input)%bn254PairingSize != 0 {
+		return nil, ErrBadPairingInputSize
+	}
+
+	var g1Points []*bn254.G1
+	var g2Points []*bn254.G2
+	for i := 0; i < len(input); i += bn254PairingSize {
+		g1, err := newBN254G1Point(input[i : i+bn254G1PointSize])
+		if err != nil {
+			return nil, err
+		}
+		g2 := new(bn254.G2)
+		if _, err = g2.Unmarshal(input[i+bn254G1PointSize : i+bn254PairingSize]); err != nil {
+			return nil, err
+		}
+		g1Points = append(g1Points, g1)
+		g2Points = append(g2Points, g2)
+	}
+
+	result := make([]byte, 32)
+	if bn254.PairingCheck(g1Points, g2Points) {
+		result[31] = 1
+	}
+	return result, nil
+}
+
+// Decode a G1 point of the alt_bn128 curve.
+// It returns an error if a coordinate is not a valid field element or if the point is not on the curve.
+func newBN254G1Point(data []byte) (*bn254.G1, error) {
+	p := new(bn254.G1)
+	if _, err := p.Unmarshal(data); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
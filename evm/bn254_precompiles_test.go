@@ -0,0 +1,86 @@
+package evm
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Field modulus of the alt_bn128 curve.
+const bn254FieldModulus = "30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd47"
+
+func TestBN254Add(t *testing.T) {
+	testPrecompileVectors(t, PrecompiledContracts(Istanbul)[common.BytesToAddress([]byte{0x06})], "bn256Add")
+}
+
+func TestBN254ScalarMul(t *testing.T) {
+	testPrecompileVectors(t, PrecompiledContracts(Istanbul)[common.BytesToAddress([]byte{0x07})], "bn256ScalarMul")
+}
+
+func TestBN254Pairing(t *testing.T) {
+	testPrecompileVectors(t, PrecompiledContracts(Istanbul)[common.BytesToAddress([]byte{0x08})], "bn256Pairing")
+}
+
+func TestBN254GasCosts(t *testing.T) {
+	pairingInput := make([]byte, 2*bn254PairingSize)
+	tests := []struct {
+		fork     Fork
+		address  byte
+		input    []byte
+		expected uint64
+	}{
+		{Byzantium, 0x06, nil, 500},
+		{Istanbul, 0x06, nil, 150},
+		{Byzantium, 0x07, nil, 40000},
+		{Istanbul, 0x07, nil, 6000},
+		{Byzantium, 0x08, pairingInput, 100000 + 2*80000},
+		{Istanbul, 0x08, pairingInput, 45000 + 2*34000},
+	}
+	for _, test := range tests {
+		p := PrecompiledContracts(test.fork)[common.BytesToAddress([]byte{test.address})]
+		if gas := p.RequiredGas(test.input); gas != test.expected {
+			t.Errorf("RequiredGas() of precompile %#x at %s returned %d, wanted %d", test.address, test.fork, gas, test.expected)
+		}
+	}
+}
+
+func TestBN254InvalidInputs(t *testing.T) {
+	one := common.Bytes2Hex(common.LeftPadBytes([]byte{1}, 32))
+	two := common.Bytes2Hex(common.LeftPadBytes([]byte{2}, 32))
+	zero := common.Bytes2Hex(make([]byte, 32))
+
+	tests := []struct {
+		name    string
+		address byte
+		input   string
+	}{
+		{"ecAdd with point not on curve", 0x06, one + one + one + two},
+		{"ecAdd with coordinate above field modulus", 0x06, bn254FieldModulus + zero + one + two},
+		{"ecMul with point not on curve", 0x07, one + one + two},
+		{"ecMul with coordinate above field modulus", 0x07, one + bn254FieldModulus + two},
+		{"ecPairing with bad input size", 0x08, one + two},
+		{"ecPairing with G1 point not on curve", 0x08, one + one + zero + zero + zero + zero},
+		{"ecPairing with G2 point not on curve", 0x08, one + two + one + one + one + one},
+	}
+
+	contracts := PrecompiledContracts(Istanbul)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := contracts[common.BytesToAddress([]byte{test.address})]
+			if _, err := p.Run(common.FromHex(test.input)); err == nil {
+				t.Errorf("Run() should have returned an error")
+			}
+		})
+	}
+}
+
+func TestBN254PairingEmptyInput(t *testing.T) {
+	p := PrecompiledContracts(Istanbul)[common.BytesToAddress([]byte{0x08})]
+	output, err := p.Run(nil)
+	if err != nil {
+		t.Fatalf("Run() returned an unexpected error: %v", err)
+	}
+	if output[31] != 1 {
+		t.Errorf("Pairing check of an empty input should succeed, got %x", output)
+	}
+}
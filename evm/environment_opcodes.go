@@ -0,0 +1,45 @@
+package evm
+
+import (
+	"github.com/holiman/uint256"
+)
+
+// IEnvironmentOps defines operations that read information about the call executing the code.
+type IEnvironmentOps interface {
+	// CallValue pushes the value, in wei, passed to the account executing the code to the top of the stack.
+	// Stack: [...] -> [value, ...]
+	CallValue() error
+
+	// CallDataLoad reads a word of the input data of the call.
+	// It pops an item from the stack, this is the offset of the word in the input data.
+	// Then it pushes the 32 bytes of the input data starting at the offset, padded with zeros past its end.
+	// Stack: [offset, ...] -> [word, ...]
+	CallDataLoad() error
+
+	// CallDataSize pushes the size of the input data of the call, in bytes, to the top of the stack.
+	// Stack: [...] -> [size, ...]
+	CallDataSize() error
+}
+
+func (e *EVM) CallValue() error {
+	return e.stack.Push(new(uint256.Int).Set(e.env.value))
+}
+
+func (e *EVM) CallDataLoad() error {
+	// Load offset from the stack.
+	offset, err := e.stack.Pop()
+	if err != nil {
+		return err
+	}
+
+	// Read the word, or zero if the offset is past the end of the input data.
+	var word [32]byte
+	if offset.IsUint64() && offset.Uint64() < uint64(len(e.env.input)) {
+		copy(word[:], e.env.input[offset.Uint64():])
+	}
+	return e.stack.Push(new(uint256.Int).SetBytes32(word[:]))
+}
+
+func (e *EVM) CallDataSize() error {
+	return e.stack.Push(uint256.NewInt(uint64(len(e.env.input))))
+}
@@ -0,0 +1,41 @@
+package evm
+
+import (
+	"testing"
+
+	"github.com/holiman/uint256"
+)
+
+func TestCallValue(t *testing.T) {
+	op := func(evm IEVM) error { return evm.CallValue() }
+	evm := NewEVM(nil, WithValue(uint256.NewInt(42)))
+	testStackOperationWithExistingEVM(t, evm, op, nil, []uint64{1}, []uint64{1, 42}, nil, nil)
+}
+
+func TestCallDataSize(t *testing.T) {
+	op := func(evm IEVM) error { return evm.CallDataSize() }
+	evm := NewEVM(nil, WithInput([]byte{1, 2, 3}))
+	testStackOperationWithExistingEVM(t, evm, op, nil, nil, []uint64{3}, nil, nil)
+}
+
+func TestCallDataLoad(t *testing.T) {
+	op := func(evm IEVM) error { return evm.CallDataLoad() }
+	input := make([]byte, 33)
+	input[31], input[32] = 0x2a, 0x07
+
+	tests := []struct {
+		name     string
+		offset   uint64
+		expected uint64
+	}{
+		{"first word", 0, 0x2a},
+		{"padded with zeros", 2, 0x2a0700},
+		{"offset past the end", 33, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			evm := NewEVM(nil, WithInput(input))
+			testStackOperationWithExistingEVM(t, evm, op, nil, []uint64{test.offset}, []uint64{test.expected}, nil, nil)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package evm
 
 import (
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/holiman/uint256"
 )
 
@@ -11,49 +12,252 @@ type IEVM interface {
 	ISHA3Ops
 	IStackOps
 	IMemoryOps
+	IEnvironmentOps
+	IBlockOps
+	IFlowOps
+	ILogOps
+	ISystemOps
+	IPrecompileRegistry
+	IInterpreter
+	IStepper
+	IInspector
 }
 
 // EVM represents an Ethereum Virtual Machine.
 type EVM struct {
-	stack   IStack
-	memory  IMemory
-	storage IStorage
-	env     ExecutionEnvironment
-	state   MachineState
+	stack        IStack
+	memory       IMemory
+	storage      IStorage
+	env          ExecutionEnvironment
+	state        MachineState
+	precompiles  map[common.Address]IPrecompile
+	instructions *InstructionSet
+	jumpdests    []bool
+	tracer       ITracer
 }
 
 // ExecutionEnvironment represents the EVM execution environment.
 type ExecutionEnvironment struct {
 	// Machine code to be executed by the EVM.
 	code []byte
+
+	// Network upgrade whose rules apply to the execution.
+	fork Fork
+
+	// Amount of gas available to the execution.
+	gas uint64
+
+	// Depth of the call executing the code, zero for the top-level call.
+	depth int
+
+	// Maximum number of instructions to execute, zero for no limit.
+	maxSteps uint64
+
+	// Maximum size of the memory, in bytes, zero for no limit.
+	maxMemory uint64
+
+	// True if the invariants of the EVM are checked after each instruction.
+	paranoid bool
+
+	// Address of the account executing the code.
+	address common.Address
+
+	// Address of the account which caused the code to be executing.
+	caller common.Address
+
+	// Value, in wei, passed to the account executing the code.
+	value *uint256.Int
+
+	// Input data of the call.
+	input []byte
+
+	// Versioned hashes of the blobs carried by the transaction (EIP-4844).
+	blobHashes []common.Hash
+
+	// Blob base fee of the current block (EIP-7516).
+	blobBaseFee *uint256.Int
 }
 
 // MachineState represents the EVM state.
 type MachineState struct {
 	// Program counter.
 	pc int
+
+	// Gas left.
+	gas uint64
+
+	// Number of instructions executed.
+	steps uint64
+
+	// Gas to refund at the end of the execution.
+	refund uint64
+
+	// Data returned by RETURN or REVERT.
+	returnData []byte
+
+	// Events emitted by the execution.
+	logs []*Log
+
+	// True once the start of the execution was reported to the tracer.
+	entered bool
+
+	// True once the execution halted.
+	halted bool
+
+	// Error which halted the execution, if any.
+	err error
 }
 
+// Option represents a function that configures an EVM instance.
+type Option func(*EVM)
+
 // NewEVM creates and returns a new EVM instance.
-func NewEVM(code []byte) IEVM {
-	return &EVM{
+func NewEVM(code []byte, opts ...Option) IEVM {
+	evm := &EVM{
 		stack:   NewStack(),
 		memory:  NewMemory(),
 		storage: NewStorage(),
 		env: ExecutionEnvironment{
-			code: code,
+			code:        code,
+			fork:        LatestFork,
+			gas:         DefaultGasLimit,
+			value:       new(uint256.Int),
+			blobBaseFee: new(uint256.Int),
 		},
 		state: MachineState{
-			pc: 0,
+			pc:  0,
+			gas: DefaultGasLimit,
 		},
 	}
+	for _, opt := range opts {
+		opt(evm)
+	}
+
+	// Load the built-in precompiled contracts once the fork is known.
+	evm.precompiles = PrecompiledContracts(evm.env.fork)
+	if evm.instructions == nil {
+		evm.instructions = NewInstructionSet(evm.env.fork)
+	}
+	return evm
+}
+
+// WithFork sets the network upgrade whose rules apply to the execution.
+// It defaults to the latest fork.
+func WithFork(fork Fork) Option {
+	return func(e *EVM) {
+		e.env.fork = fork
+	}
+}
+
+// WithGas sets the amount of gas available to the execution.
+// It defaults to DefaultGasLimit.
+func WithGas(gas uint64) Option {
+	return func(e *EVM) {
+		e.env.gas = gas
+		e.state.gas = gas
+	}
+}
+
+// WithInstructionSet sets the instruction set executed by the interpreter.
+// The set is copied, so custom operations registered later on the EVM don't leak into it.
+// It defaults to the instruction set of the fork.
+func WithInstructionSet(set *InstructionSet) Option {
+	return func(e *EVM) {
+		instructions := *set
+		e.instructions = &instructions
+	}
+}
+
+// WithMaxSteps sets the maximum number of instructions to execute.
+// The execution halts with ErrStepLimit once it is reached.
+// It defaults to zero, i.e. no limit.
+func WithMaxSteps(steps uint64) Option {
+	return func(e *EVM) {
+		e.env.maxSteps = steps
+	}
+}
+
+// WithMaxMemory sets the maximum size of the memory, in bytes.
+// The execution halts with ErrMemoryLimit when an instruction expands the memory beyond it.
+// It defaults to zero, i.e. no limit other than the gas.
+func WithMaxMemory(size uint64) Option {
+	return func(e *EVM) {
+		e.env.maxMemory = size
+	}
+}
+
+// WithParanoidMode checks the invariants of the EVM, e.g. the stack and memory sizes, after each instruction.
+// The execution halts with ErrInternal if one of them doesn't hold.
+// It slows down the execution and is meant to catch bugs in the interpreter and in custom operations.
+func WithParanoidMode() Option {
+	return func(e *EVM) {
+		e.env.paranoid = true
+	}
+}
+
+// WithDepth sets the depth of the call executing the code.
+// It defaults to zero, i.e. the top-level call.
+func WithDepth(depth int) Option {
+	return func(e *EVM) {
+		e.env.depth = depth
+	}
+}
+
+// WithStorage sets the storage of the account executing the code.
+// It defaults to an empty storage.
+func WithStorage(storage IStorage) Option {
+	return func(e *EVM) {
+		e.storage = storage
+	}
+}
+
+// WithAddress sets the address of the account executing the code.
+func WithAddress(address common.Address) Option {
+	return func(e *EVM) {
+		e.env.address = address
+	}
+}
+
+// WithCaller sets the address of the account which caused the code to be executing.
+func WithCaller(caller common.Address) Option {
+	return func(e *EVM) {
+		e.env.caller = caller
+	}
+}
+
+// WithValue sets the value, in wei, passed to the account executing the code.
+func WithValue(value *uint256.Int) Option {
+	return func(e *EVM) {
+		e.env.value = new(uint256.Int).Set(value)
+	}
+}
+
+// WithInput sets the input data of the call.
+func WithInput(input []byte) Option {
+	return func(e *EVM) {
+		e.env.input = append([]byte{}, input...)
+	}
+}
+
+// WithBlobHashes sets the versioned hashes of the blobs carried by the transaction.
+func WithBlobHashes(hashes ...common.Hash) Option {
+	return func(e *EVM) {
+		e.env.blobHashes = hashes
+	}
+}
+
+// WithBlobBaseFee sets the blob base fee of the current block.
+func WithBlobBaseFee(fee *uint256.Int) Option {
+	return func(e *EVM) {
+		e.env.blobBaseFee = new(uint256.Int).Set(fee)
+	}
 }
 
 // Perform an arithmetic or a bitwise operation on the top two elements on the stack.
 // It pops two values from the stack, applies the operation, and pushes the result back to the stack.
 func (e *EVM) performBinaryStackOperation(numOperands int, operation func(...*uint256.Int) *uint256.Int) error {
 	// Check if there are enough elements on the stack.
-	if e.stack.Size() <= numOperands {
+	if e.stack.Size() < numOperands {
 		return ErrStackUnderflow
 	}
 
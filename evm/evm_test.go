@@ -46,6 +46,15 @@ func TestNewEVM(t *testing.T) {
 	}
 }
 
+func TestWithStorage(t *testing.T) {
+	storage := NewStorage()
+	storage.Store(1, [32]byte{31: 0x2a})
+	evm := NewEVM(nil, WithStorage(storage))
+	if value := evm.StorageDump()[1]; value != [32]byte{31: 0x2a} {
+		t.Errorf("Expected the storage to be used by the EVM, got %x at key 1", value)
+	}
+}
+
 func TestStackOperationUnderflows(t *testing.T) {
 	var emptyStack []uint64
 	oneElementStack := []uint64{1}
@@ -68,6 +77,14 @@ func TestStackOperationUnderflows(t *testing.T) {
 	testStackOperationWithNewEVM(t, eqOp, ErrStackUnderflow, oneElementStack, emptyStack, nil, nil, nil)
 }
 
+func TestStackOperationWithExactOperands(t *testing.T) {
+	addOp := func(evm IEVM) error { return evm.Add() }
+	testStackOperationWithNewEVM(t, addOp, nil, []uint64{1, 2}, []uint64{3}, nil, nil, nil)
+
+	addModOp := func(evm IEVM) error { return evm.AddMod() }
+	testStackOperationWithNewEVM(t, addModOp, nil, []uint64{5, 2, 1}, []uint64{3}, nil, nil, nil)
+}
+
 func TestStackOperationOnFullStack(t *testing.T) {
 	op := func(evm IEVM) error { return evm.Add() }
 
@@ -0,0 +1,77 @@
+package evm
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/holiman/uint256"
+)
+
+// ExecutionResult represents the outcome of an execution.
+type ExecutionResult struct {
+	// Amount of gas consumed by the execution, before the refund.
+	// An exceptional halt consumes all the gas.
+	GasUsed uint64
+
+	// Amount of gas refunded at the end of the execution, capped by EIP-3529.
+	GasRefunded uint64
+
+	// Data returned by RETURN or REVERT.
+	ReturnData []byte
+
+	// True if the execution was halted by REVERT.
+	Reverted bool
+
+	// Reason decoded from the revert data, if it is an Error(string) or a Panic(uint256).
+	RevertReason string
+
+	// Events emitted by the execution.
+	// They are discarded if the execution reverted or failed.
+	Logs []*Log
+
+	// Program counter at which the execution halted.
+	PC int
+
+	// Items on the stack when the execution halted, from the bottom to the top.
+	Stack []*uint256.Int
+
+	// Error which halted the execution, if any.
+	// It is ErrExecutionReverted if the execution was halted by REVERT.
+	Err error
+}
+
+// Failed returns true if the execution reverted or halted with an error.
+func (r *ExecutionResult) Failed() bool {
+	return r.Err != nil
+}
+
+// Build the result of the execution.
+func (e *EVM) result() *ExecutionResult {
+	err := e.state.err
+	reverted := errors.Is(err, ErrExecutionReverted)
+	result := &ExecutionResult{
+		GasUsed:    e.env.gas - e.state.gas,
+		ReturnData: e.state.returnData,
+		Reverted:   reverted,
+		PC:         e.state.pc,
+		Stack:      e.StackItems(),
+		Err:        err,
+	}
+	if err == nil {
+		result.Logs = e.state.logs
+		result.GasRefunded = min(e.state.refund, result.GasUsed/maxRefundQuotient(e.env.fork))
+	}
+	if reverted {
+		result.RevertReason, _ = abi.UnpackRevert(e.state.returnData)
+	}
+	return result
+}
+
+// Return the maximum ratio between the gas used and the gas refunded.
+// It was reduced by EIP-3529 in London.
+func maxRefundQuotient(fork Fork) uint64 {
+	if fork >= London {
+		return 5
+	}
+	return 2
+}
@@ -0,0 +1,111 @@
+package evm
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestExecutionResult(t *testing.T) {
+	// PUSH1 0x2a, PUSH1 0, MSTORE, PUSH1 7, PUSH1 32, PUSH1 0, RETURN
+	code := common.FromHex("0x602a600052600760206000f3")
+	result := NewEVM(code, WithGas(100)).Run()
+	if result.Err != nil {
+		t.Fatalf("Run() returned an unexpected error: %v", result.Err)
+	}
+	if result.Failed() || result.Reverted {
+		t.Errorf("Execution should not have failed")
+	}
+	// 5 pushes, MSTORE and 1 word of memory.
+	if result.GasUsed != 21 {
+		t.Errorf("Expected 21 gas used, got %d", result.GasUsed)
+	}
+	if expected := common.LeftPadBytes([]byte{0x2a}, 32); !bytes.Equal(result.ReturnData, expected) {
+		t.Errorf("Expected return data %x, got %x", expected, result.ReturnData)
+	}
+	if result.PC != 11 {
+		t.Errorf("Expected pc 11, got %d", result.PC)
+	}
+	if len(result.Stack) != 1 || result.Stack[0].Uint64() != 7 {
+		t.Errorf("Expected stack [7], got %v", result.Stack)
+	}
+}
+
+func TestExecutionResultRevert(t *testing.T) {
+	// Store the ABI encoding of Error("boom") in memory, emit a log and revert.
+	revertData := common.FromHex("0x08c379a0" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"0000000000000000000000000000000000000000000000000000000000000004" +
+		"626f6f6d00000000000000000000000000000000000000000000000000000000")
+	var code []byte
+	for i, b := range revertData {
+		code = append(code, 0x60, b, 0x60, byte(i), 0x53) // PUSH1 b, PUSH1 i, MSTORE8
+	}
+	code = append(code, 0x60, 0x00, 0x60, 0x00, 0xa0)                  // PUSH1 0, PUSH1 0, LOG0
+	code = append(code, 0x60, byte(len(revertData)), 0x60, 0x00, 0xfd) // PUSH1 size, PUSH1 0, REVERT
+
+	result := NewEVM(code, WithGas(10_000)).Run()
+	if !errors.Is(result.Err, ErrExecutionReverted) || !result.Reverted {
+		t.Fatalf("Run() returned an unexpected error: %v, wanted: %v", result.Err, ErrExecutionReverted)
+	}
+	if !bytes.Equal(result.ReturnData, revertData) {
+		t.Errorf("Expected return data %x, got %x", revertData, result.ReturnData)
+	}
+	if result.RevertReason != "boom" {
+		t.Errorf("Expected revert reason boom, got %q", result.RevertReason)
+	}
+	if len(result.Logs) != 0 {
+		t.Errorf("Logs should be discarded on revert, got %d", len(result.Logs))
+	}
+	if result.GasUsed >= 10_000 {
+		t.Errorf("REVERT should not consume all the gas")
+	}
+}
+
+func TestExecutionResultLogs(t *testing.T) {
+	// PUSH1 0, PUSH1 0, LOG0
+	result := NewEVM([]byte{0x60, 0x00, 0x60, 0x00, 0xa0}).Run()
+	if result.Err != nil {
+		t.Fatalf("Run() returned an unexpected error: %v", result.Err)
+	}
+	if len(result.Logs) != 1 {
+		t.Errorf("Expected 1 log, got %d", len(result.Logs))
+	}
+}
+
+func TestExecutionResultExceptionalHalt(t *testing.T) {
+	// PUSH1 1, INVALID
+	result := NewEVM([]byte{0x60, 0x01, 0xef}, WithGas(100)).Run()
+	if !errors.Is(result.Err, ErrInvalidOpcode) || !result.Failed() {
+		t.Fatalf("Run() returned an unexpected error: %v, wanted: %v", result.Err, ErrInvalidOpcode)
+	}
+	if result.GasUsed != 100 {
+		t.Errorf("An exceptional halt should consume all the gas, got %d", result.GasUsed)
+	}
+	if result.PC != 2 {
+		t.Errorf("Expected pc 2, got %d", result.PC)
+	}
+	if result.Reverted {
+		t.Errorf("Execution should not be reverted")
+	}
+}
+
+func TestExecutionResultRefund(t *testing.T) {
+	refund := &Operation{
+		Name:        "REFUND",
+		ConstantGas: 100,
+		Execute:     func(ctx *OperationContext) error { ctx.AddRefund(1000); return nil },
+	}
+	evm := NewEVM([]byte{0xef}, WithFork(London))
+	if err := evm.RegisterOperation(0xef, refund); err != nil {
+		t.Fatalf("RegisterOperation() returned an unexpected error: %v", err)
+	}
+
+	// The refund is capped to a fifth of the gas used since London.
+	result := evm.Run()
+	if result.GasRefunded != 20 {
+		t.Errorf("Expected 20 gas refunded, got %d", result.GasRefunded)
+	}
+}
@@ -0,0 +1,97 @@
+package evm
+
+import (
+	"errors"
+
+	"github.com/holiman/uint256"
+)
+
+var (
+	// ErrInvalidJump is returned when a jump targets a position which isn't a JUMPDEST instruction.
+	ErrInvalidJump = errors.New("invalid jump destination")
+)
+
+// IFlowOps defines operations which alter the flow of the execution.
+type IFlowOps interface {
+	// Jump moves the program counter to a JUMPDEST instruction.
+	// It pops an item from the stack, this is the destination.
+	// It returns ErrInvalidJump if the destination isn't a JUMPDEST instruction, e.g. inside the data of a PUSH.
+	// Stack: [destination, ...] -> [...]
+	Jump() error
+
+	// JumpI moves the program counter to a JUMPDEST instruction if a condition is not zero.
+	// It pops two items from the stack, the destination and the condition.
+	// It returns ErrInvalidJump if the condition is not zero and the destination isn't a JUMPDEST instruction.
+	// Stack: [destination, condition, ...] -> [...]
+	JumpI() error
+
+	// JumpDest marks a valid destination for jumps, and has no effect.
+	// Stack: [...] -> [...]
+	JumpDest() error
+}
+
+func (e *EVM) Jump() error {
+	// Load destination from the stack.
+	destination, err := e.stack.Pop()
+	if err != nil {
+		return err
+	}
+	return e.jump(destination)
+}
+
+func (e *EVM) JumpI() error {
+	// Load destination from the stack.
+	destination, err := e.stack.Pop()
+	if err != nil {
+		return err
+	}
+
+	// Load condition from the stack.
+	condition, err := e.stack.Pop()
+	if err != nil {
+		return err
+	}
+
+	if condition.IsZero() {
+		return nil
+	}
+	return e.jump(destination)
+}
+
+func (e *EVM) JumpDest() error {
+	return nil
+}
+
+// Move the program counter to the destination, if it is a JUMPDEST instruction.
+func (e *EVM) jump(destination *uint256.Int) error {
+	if !destination.IsUint64() || destination.Uint64() >= uint64(len(e.env.code)) {
+		return ErrInvalidJump
+	}
+	pc := int(destination.Uint64())
+	if OpCode(e.env.code[pc]) != JUMPDEST {
+		return ErrInvalidJump
+	}
+
+	// The analysis is only done once the code jumps, as most instructions don't need it.
+	if e.jumpdests == nil {
+		e.jumpdests = analyzeJumpDests(e.env.code, e.instructions)
+	}
+	if !e.jumpdests[pc] {
+		return ErrInvalidJump
+	}
+	e.state.pc = pc
+	return nil
+}
+
+// Return the positions of the code which are instructions, and not immediate data.
+// The immediate data sizes are read from the instruction set, so the data of custom operations is skipped like the data of PUSH instructions.
+func analyzeJumpDests(code []byte, instructions *InstructionSet) []bool {
+	instructionStarts := make([]bool, len(code))
+	for pc := 0; pc < len(code); pc++ {
+		instructionStarts[pc] = true
+		if op := instructions[OpCode(code[pc])]; op != nil {
+			pc += op.ImmediateSize
+		}
+	}
+	return instructionStarts
+}
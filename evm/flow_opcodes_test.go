@@ -0,0 +1,68 @@
+package evm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestJump(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     []byte
+		expected error
+		stack    int
+	}{
+		// PUSH1 4, JUMP, INVALID, JUMPDEST, PUSH0
+		{"jump", []byte{0x60, 0x04, 0x56, 0xfe, 0x5b, 0x5f}, nil, 1},
+		// PUSH1 1, PUSH1 6, JUMPI, INVALID, INVALID, INVALID, JUMPDEST
+		{"condition true", []byte{0x60, 0x01, 0x60, 0x06, 0x57, 0xfe, 0x5b}, nil, 0},
+		// PUSH0, PUSH1 6, JUMPI, PUSH0, STOP
+		{"condition false", []byte{0x5f, 0x60, 0x06, 0x57, 0x5f, 0x00}, nil, 1},
+		// PUSH1 3, JUMP, PUSH1 0x5b: the destination is inside the data of the PUSH.
+		{"destination in push data", []byte{0x60, 0x03, 0x56, 0x60, 0x5b}, ErrInvalidJump, 0},
+		// PUSH1 2, JUMP, STOP: the destination isn't a JUMPDEST.
+		{"destination not jumpdest", []byte{0x60, 0x02, 0x56, 0x00}, ErrInvalidJump, 0},
+		// PUSH1 10, JUMP
+		{"destination out of range", []byte{0x60, 0x0a, 0x56}, ErrInvalidJump, 0},
+		// PUSH0, PUSH1 5, JUMPI, JUMPDEST: a false condition doesn't check the destination.
+		{"invalid destination with condition false", []byte{0x5f, 0x60, 0x05, 0x57, 0x5b}, nil, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := NewEVM(test.code).Run()
+			if !errors.Is(result.Err, test.expected) {
+				t.Fatalf("Run() returned an unexpected error: %v, wanted: %v", result.Err, test.expected)
+			}
+			if test.expected == nil && len(result.Stack) != test.stack {
+				t.Errorf("Expected %d stack items, got %d", test.stack, len(result.Stack))
+			}
+		})
+	}
+}
+
+func TestJumpGas(t *testing.T) {
+	// PUSH1 1, PUSH1 6, JUMPI, INVALID, JUMPDEST, PUSH1 11, JUMP, INVALID, JUMPDEST
+	code := []byte{0x60, 0x01, 0x60, 0x06, 0x57, 0xfe, 0x5b, 0x60, 0x0b, 0x56, 0xfe, 0x5b}
+	result := NewEVM(code).Run()
+	if expected := uint64(3 + 3 + 10 + 1 + 3 + 8 + 1); result.Err != nil || result.GasUsed != expected {
+		t.Errorf("Run() used %d gas with error %v, wanted %d", result.GasUsed, result.Err, expected)
+	}
+}
+
+func TestJumpOverCustomOperationData(t *testing.T) {
+	// PUSH1 4, JUMP, PUSHPAIR 0x5b5b, JUMPDEST: the destination is inside the data of PUSHPAIR.
+	code := []byte{0x60, 0x04, 0x56, 0x0c, 0x5b, 0x5b}
+	evm := NewEVM(code)
+	err := evm.RegisterOperation(0x0c, &Operation{Name: "PUSHPAIR", ImmediateSize: 2, Execute: func(ctx *OperationContext) error { return nil }})
+	if err != nil {
+		t.Fatalf("RegisterOperation() returned an unexpected error: %v", err)
+	}
+	if result := evm.Run(); !errors.Is(result.Err, ErrInvalidJump) {
+		t.Errorf("Run() returned an unexpected error: %v, wanted: %v", result.Err, ErrInvalidJump)
+	}
+
+	// Without the operation, the opcode is undefined and the destination is a JUMPDEST.
+	if result := NewEVM(code).Run(); result.Err != nil {
+		t.Errorf("Run() returned an unexpected error: %v", result.Err)
+	}
+}
@@ -0,0 +1,58 @@
+package evm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Fork represents an Ethereum network upgrade.
+// Forks are ordered chronologically so they can be compared with the usual operators.
+type Fork int
+
+const (
+	Frontier Fork = iota
+	Homestead
+	Byzantium
+	Constantinople
+	Istanbul
+	Berlin
+	London
+	Shanghai
+	Cancun
+	Prague
+	Osaka
+)
+
+// LatestFork is the most recent network upgrade supported by the EVM.
+const LatestFork = Osaka
+
+var forkNames = map[Fork]string{
+	Frontier:       "frontier",
+	Homestead:      "homestead",
+	Byzantium:      "byzantium",
+	Constantinople: "constantinople",
+	Istanbul:       "istanbul",
+	Berlin:         "berlin",
+	London:         "london",
+	Shanghai:       "shanghai",
+	Cancun:         "cancun",
+	Prague:         "prague",
+	Osaka:          "osaka",
+}
+
+func (f Fork) String() string {
+	if name, ok := forkNames[f]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ParseFork returns the network upgrade with the given name, case-insensitively.
+func ParseFork(name string) (Fork, error) {
+	for fork, forkName := range forkNames {
+		if strings.EqualFold(name, forkName) {
+			return fork, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown fork %q", name)
+}
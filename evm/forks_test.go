@@ -0,0 +1,17 @@
+package evm
+
+import "testing"
+
+func TestParseFork(t *testing.T) {
+	for fork, name := range forkNames {
+		if parsed, err := ParseFork(name); err != nil || parsed != fork {
+			t.Errorf("ParseFork(%q) returned %v, %v, wanted: %v", name, parsed, err, fork)
+		}
+	}
+	if fork, err := ParseFork("Cancun"); err != nil || fork != Cancun {
+		t.Errorf("ParseFork(\"Cancun\") returned %v, %v, wanted: %v", fork, err, Cancun)
+	}
+	if _, err := ParseFork("merge"); err == nil {
+		t.Errorf("ParseFork(\"merge\") returned no error")
+	}
+}
@@ -0,0 +1,49 @@
+package evm
+
+import (
+	"github.com/holiman/uint256"
+)
+
+// IInspector defines read-only methods to observe the state of an EVM instance.
+// All methods return copies, so the caller can't modify the state of the EVM through them.
+type IInspector interface {
+	// StackItems returns the items on the stack, from the bottom to the top.
+	StackItems() []*uint256.Int
+
+	// MemoryBytes returns the content of the memory.
+	MemoryBytes() []byte
+
+	// StorageDump returns the key-value pairs in storage.
+	StorageDump() map[int][32]byte
+
+	// PC returns the program counter.
+	PC() int
+
+	// Gas returns the amount of gas left.
+	Gas() uint64
+}
+
+func (e *EVM) StackItems() []*uint256.Int {
+	items := make([]*uint256.Int, e.stack.Size())
+	for i := range items {
+		item, _ := e.stack.Get(len(items) - i)
+		items[i] = new(uint256.Int).Set(item)
+	}
+	return items
+}
+
+func (e *EVM) MemoryBytes() []byte {
+	return append([]byte{}, e.memory.Load(0, e.memory.Size())...)
+}
+
+func (e *EVM) StorageDump() map[int][32]byte {
+	return e.storage.Dump()
+}
+
+func (e *EVM) PC() int {
+	return e.state.pc
+}
+
+func (e *EVM) Gas() uint64 {
+	return e.state.gas
+}
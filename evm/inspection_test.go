@@ -0,0 +1,76 @@
+package evm
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestStackItems(t *testing.T) {
+	// PUSH1 1, PUSH1 2
+	evm := NewEVM([]byte{0x60, 0x01, 0x60, 0x02})
+	if result := evm.Run(); result.Err != nil {
+		t.Fatalf("Run() returned an unexpected error: %v", result.Err)
+	}
+
+	items := evm.StackItems()
+	if len(items) != 2 || items[0].Uint64() != 1 || items[1].Uint64() != 2 {
+		t.Fatalf("StackItems() returned %v, wanted [1 2]", items)
+	}
+
+	// Modifying the items should not modify the stack.
+	items[1].SetUint64(42)
+	if item := evm.StackItems()[1]; item.Uint64() != 2 {
+		t.Errorf("StackItems() should return a copy of the stack, got %v", item)
+	}
+}
+
+func TestMemoryBytes(t *testing.T) {
+	// PUSH1 0xff, PUSH1 0, MSTORE8
+	evm := NewEVM([]byte{0x60, 0xff, 0x60, 0x00, 0x53})
+	if result := evm.Run(); result.Err != nil {
+		t.Fatalf("Run() returned an unexpected error: %v", result.Err)
+	}
+
+	memory := evm.MemoryBytes()
+	if expected := append([]byte{0xff}, make([]byte, 31)...); !bytes.Equal(memory, expected) {
+		t.Fatalf("MemoryBytes() returned %x, wanted %x", memory, expected)
+	}
+
+	// Modifying the bytes should not modify the memory.
+	memory[0] = 0x00
+	if evm.MemoryBytes()[0] != 0xff {
+		t.Errorf("MemoryBytes() should return a copy of the memory")
+	}
+}
+
+func TestStorageDump(t *testing.T) {
+	evm := NewEVM(nil)
+	evm.(*EVM).storage.Store(3, [32]byte{31: 0x2a})
+
+	dump := evm.StorageDump()
+	if len(dump) != 1 || dump[3] != [32]byte{31: 0x2a} {
+		t.Errorf("StorageDump() returned %v, wanted a single value at key 3", dump)
+	}
+}
+
+func TestPCAndGas(t *testing.T) {
+	evm := NewEVM([]byte{0x60, 0x01, 0x00}, WithGas(10))
+	if pc, gas := evm.PC(), evm.Gas(); pc != 0 || gas != 10 {
+		t.Errorf("Expected pc 0 and 10 gas before the execution, got pc %d and %d gas", pc, gas)
+	}
+
+	evm.Run()
+	if pc, gas := evm.PC(), evm.Gas(); pc != 2 || gas != 7 {
+		t.Errorf("Expected pc 2 and 7 gas after the execution, got pc %d and %d gas", pc, gas)
+	}
+}
+
+func TestInspectorOnEmptyEVM(t *testing.T) {
+	evm := NewEVM(nil)
+	if items := evm.StackItems(); len(items) != 0 {
+		t.Errorf("StackItems() returned %v, wanted an empty slice", items)
+	}
+	if memory := evm.MemoryBytes(); len(memory) != 0 {
+		t.Errorf("MemoryBytes() returned %x, wanted an empty slice", memory)
+	}
+}
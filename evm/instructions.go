@@ -0,0 +1,429 @@
+package evm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/holiman/uint256"
+)
+
+var (
+	// ErrOpcodeAlreadyDefined is returned when registering an operation for an opcode which is already used.
+	ErrOpcodeAlreadyDefined = errors.New("opcode already defined")
+	// ErrInvalidOperation is returned when registering an operation without a name or an execute function.
+	ErrInvalidOperation = errors.New("invalid operation")
+)
+
+// OpCode represents an EVM instruction.
+type OpCode byte
+
+const (
+	STOP       OpCode = 0x00
+	ADD        OpCode = 0x01
+	MUL        OpCode = 0x02
+	SUB        OpCode = 0x03
+	DIV        OpCode = 0x04
+	SDIV       OpCode = 0x05
+	MOD        OpCode = 0x06
+	SMOD       OpCode = 0x07
+	ADDMOD     OpCode = 0x08
+	MULMOD     OpCode = 0x09
+	EXP        OpCode = 0x0a
+	SIGNEXTEND OpCode = 0x0b
+
+	LT     OpCode = 0x10
+	GT     OpCode = 0x11
+	SLT    OpCode = 0x12
+	SGT    OpCode = 0x13
+	EQ     OpCode = 0x14
+	ISZERO OpCode = 0x15
+	AND    OpCode = 0x16
+	OR     OpCode = 0x17
+	XOR    OpCode = 0x18
+	NOT    OpCode = 0x19
+	BYTE   OpCode = 0x1a
+	SHL    OpCode = 0x1b
+	SHR    OpCode = 0x1c
+	SAR    OpCode = 0x1d
+	CLZ    OpCode = 0x1e
+
+	KECCAK256 OpCode = 0x20
+
+	ADDRESS        OpCode = 0x30
+	BALANCE        OpCode = 0x31
+	ORIGIN         OpCode = 0x32
+	CALLER         OpCode = 0x33
+	CALLVALUE      OpCode = 0x34
+	CALLDATALOAD   OpCode = 0x35
+	CALLDATASIZE   OpCode = 0x36
+	CALLDATACOPY   OpCode = 0x37
+	CODESIZE       OpCode = 0x38
+	CODECOPY       OpCode = 0x39
+	GASPRICE       OpCode = 0x3a
+	EXTCODESIZE    OpCode = 0x3b
+	EXTCODECOPY    OpCode = 0x3c
+	RETURNDATASIZE OpCode = 0x3d
+	RETURNDATACOPY OpCode = 0x3e
+	EXTCODEHASH    OpCode = 0x3f
+
+	BLOCKHASH   OpCode = 0x40
+	COINBASE    OpCode = 0x41
+	TIMESTAMP   OpCode = 0x42
+	NUMBER      OpCode = 0x43
+	PREVRANDAO  OpCode = 0x44
+	GASLIMIT    OpCode = 0x45
+	CHAINID     OpCode = 0x46
+	SELFBALANCE OpCode = 0x47
+	BASEFEE     OpCode = 0x48
+	BLOBHASH    OpCode = 0x49
+	BLOBBASEFEE OpCode = 0x4a
+
+	POP      OpCode = 0x50
+	MLOAD    OpCode = 0x51
+	MSTORE   OpCode = 0x52
+	MSTORE8  OpCode = 0x53
+	SLOAD    OpCode = 0x54
+	SSTORE   OpCode = 0x55
+	JUMP     OpCode = 0x56
+	JUMPI    OpCode = 0x57
+	PC       OpCode = 0x58
+	MSIZE    OpCode = 0x59
+	GAS      OpCode = 0x5a
+	JUMPDEST OpCode = 0x5b
+	TLOAD    OpCode = 0x5c
+	TSTORE   OpCode = 0x5d
+	MCOPY    OpCode = 0x5e
+
+	PUSH0  OpCode = 0x5f
+	PUSH1  OpCode = 0x60
+	PUSH32 OpCode = 0x7f
+	DUP1   OpCode = 0x80
+	DUP16  OpCode = 0x8f
+	SWAP1  OpCode = 0x90
+	SWAP16 OpCode = 0x9f
+
+	LOG0 OpCode = 0xa0
+	LOG4 OpCode = 0xa4
+
+	CREATE       OpCode = 0xf0
+	CALL         OpCode = 0xf1
+	CALLCODE     OpCode = 0xf2
+	RETURN       OpCode = 0xf3
+	DELEGATECALL OpCode = 0xf4
+	CREATE2      OpCode = 0xf5
+	STATICCALL   OpCode = 0xfa
+	REVERT       OpCode = 0xfd
+	INVALID      OpCode = 0xfe
+	SELFDESTRUCT OpCode = 0xff
+)
+
+// standardNames maps the opcodes of the latest fork to their names, including those the EVM doesn't implement.
+var standardNames = map[OpCode]string{
+	STOP: "STOP", ADD: "ADD", MUL: "MUL", SUB: "SUB", DIV: "DIV", SDIV: "SDIV", MOD: "MOD", SMOD: "SMOD",
+	ADDMOD: "ADDMOD", MULMOD: "MULMOD", EXP: "EXP", SIGNEXTEND: "SIGNEXTEND",
+
+	LT: "LT", GT: "GT", SLT: "SLT", SGT: "SGT", EQ: "EQ", ISZERO: "ISZERO", AND: "AND", OR: "OR",
+	XOR: "XOR", NOT: "NOT", BYTE: "BYTE", SHL: "SHL", SHR: "SHR", SAR: "SAR", CLZ: "CLZ",
+
+	KECCAK256: "KECCAK256",
+
+	ADDRESS: "ADDRESS", BALANCE: "BALANCE", ORIGIN: "ORIGIN", CALLER: "CALLER", CALLVALUE: "CALLVALUE",
+	CALLDATALOAD: "CALLDATALOAD", CALLDATASIZE: "CALLDATASIZE", CALLDATACOPY: "CALLDATACOPY", CODESIZE: "CODESIZE",
+	CODECOPY: "CODECOPY", GASPRICE: "GASPRICE", EXTCODESIZE: "EXTCODESIZE", EXTCODECOPY: "EXTCODECOPY",
+	RETURNDATASIZE: "RETURNDATASIZE", RETURNDATACOPY: "RETURNDATACOPY", EXTCODEHASH: "EXTCODEHASH",
+
+	BLOCKHASH: "BLOCKHASH", COINBASE: "COINBASE", TIMESTAMP: "TIMESTAMP", NUMBER: "NUMBER", PREVRANDAO: "PREVRANDAO",
+	GASLIMIT: "GASLIMIT", CHAINID: "CHAINID", SELFBALANCE: "SELFBALANCE", BASEFEE: "BASEFEE", BLOBHASH: "BLOBHASH",
+	BLOBBASEFEE: "BLOBBASEFEE",
+
+	POP: "POP", MLOAD: "MLOAD", MSTORE: "MSTORE", MSTORE8: "MSTORE8", SLOAD: "SLOAD", SSTORE: "SSTORE", JUMP: "JUMP",
+	JUMPI: "JUMPI", PC: "PC", MSIZE: "MSIZE", GAS: "GAS", JUMPDEST: "JUMPDEST", TLOAD: "TLOAD", TSTORE: "TSTORE",
+	MCOPY: "MCOPY", PUSH0: "PUSH0",
+
+	CREATE: "CREATE", CALL: "CALL", CALLCODE: "CALLCODE", RETURN: "RETURN", DELEGATECALL: "DELEGATECALL",
+	CREATE2: "CREATE2", STATICCALL: "STATICCALL", REVERT: "REVERT", INVALID: "INVALID", SELFDESTRUCT: "SELFDESTRUCT",
+}
+
+// standardOpCodes maps the standard names to their opcodes.
+var standardOpCodes = make(map[string]OpCode)
+
+func init() {
+	for i := 0; i < 32; i++ {
+		standardNames[PUSH1+OpCode(i)] = fmt.Sprintf("PUSH%d", i+1)
+	}
+	for i := 0; i < 16; i++ {
+		standardNames[DUP1+OpCode(i)] = fmt.Sprintf("DUP%d", i+1)
+		standardNames[SWAP1+OpCode(i)] = fmt.Sprintf("SWAP%d", i+1)
+	}
+	for i := 0; i <= 4; i++ {
+		standardNames[LOG0+OpCode(i)] = fmt.Sprintf("LOG%d", i)
+	}
+	for opcode, name := range standardNames {
+		standardOpCodes[name] = opcode
+	}
+	// Alias used by older compilers and tools.
+	standardOpCodes["SHA3"] = KECCAK256
+}
+
+// StandardName returns the name of the opcode in the latest fork, including instructions the EVM doesn't implement.
+// The boolean is false if the opcode is undefined.
+func StandardName(opcode OpCode) (string, bool) {
+	name, ok := standardNames[opcode]
+	return name, ok
+}
+
+// LookupOpCode returns the opcode with the given standard name, ignoring the case.
+// SHA3 is accepted as an alias of KECCAK256.
+func LookupOpCode(name string) (OpCode, bool) {
+	opcode, ok := standardOpCodes[strings.ToUpper(name)]
+	return opcode, ok
+}
+
+// Operation defines an instruction of the EVM and how the interpreter executes it.
+type Operation struct {
+	// Name of the instruction (e.g. "ADD").
+	Name string
+
+	// Number of items popped from the stack.
+	StackIn int
+
+	// Number of items pushed to the stack.
+	StackOut int
+
+	// Number of bytes following the opcode in the code (e.g. 1 for PUSH1).
+	// The interpreter skips them after executing the operation, and the operation can read them with OperationContext.Immediate.
+	ImmediateSize int
+
+	// Static gas cost of the instruction.
+	ConstantGas uint64
+
+	// DynamicGas returns the gas cost which depends on the operands, on top of the static gas cost.
+	// It does not include the cost of the memory expansion, which is charged by the interpreter.
+	// It is optional.
+	DynamicGas func(ctx *OperationContext) (uint64, error)
+
+	// MemorySize returns the size of the memory, in bytes, required by the instruction.
+	// The boolean is true if the size does not fit in 64 bits.
+	// It is optional.
+	MemorySize func(stack IStack) (uint64, bool)
+
+	// Execute performs the instruction.
+	// If it doesn't move the program counter, the interpreter moves to the next instruction.
+	Execute func(ctx *OperationContext) error
+
+	// Halts is true if the instruction stops the execution.
+	Halts bool
+}
+
+// InstructionSet maps each opcode to the operation executed by the interpreter.
+// Unused opcodes are mapped to nil.
+type InstructionSet [256]*Operation
+
+// NewInstructionSet creates and returns the instruction set active at the given fork.
+func NewInstructionSet(fork Fork) *InstructionSet {
+	set := &InstructionSet{
+		STOP: {Name: "STOP", Execute: func(ctx *OperationContext) error { return nil }, Halts: true},
+
+		ADD:        newStackOperation("ADD", 2, 1, 3, func(e *EVM) error { return e.Add() }),
+		MUL:        newStackOperation("MUL", 2, 1, 5, func(e *EVM) error { return e.Mul() }),
+		SUB:        newStackOperation("SUB", 2, 1, 3, func(e *EVM) error { return e.Sub() }),
+		DIV:        newStackOperation("DIV", 2, 1, 5, func(e *EVM) error { return e.Div() }),
+		SDIV:       newStackOperation("SDIV", 2, 1, 5, func(e *EVM) error { return e.SDiv() }),
+		MOD:        newStackOperation("MOD", 2, 1, 5, func(e *EVM) error { return e.Mod() }),
+		SMOD:       newStackOperation("SMOD", 2, 1, 5, func(e *EVM) error { return e.SMod() }),
+		ADDMOD:     newStackOperation("ADDMOD", 3, 1, 8, func(e *EVM) error { return e.AddMod() }),
+		MULMOD:     newStackOperation("MULMOD", 3, 1, 8, func(e *EVM) error { return e.MulMod() }),
+		EXP:        newStackOperation("EXP", 2, 1, 10, func(e *EVM) error { return e.Exp() }),
+		SIGNEXTEND: newStackOperation("SIGNEXTEND", 2, 1, 5, func(e *EVM) error { return e.SignExtend() }),
+
+		LT:     newStackOperation("LT", 2, 1, 3, func(e *EVM) error { return e.Lt() }),
+		GT:     newStackOperation("GT", 2, 1, 3, func(e *EVM) error { return e.Gt() }),
+		SLT:    newStackOperation("SLT", 2, 1, 3, func(e *EVM) error { return e.SLt() }),
+		SGT:    newStackOperation("SGT", 2, 1, 3, func(e *EVM) error { return e.SGt() }),
+		EQ:     newStackOperation("EQ", 2, 1, 3, func(e *EVM) error { return e.Eq() }),
+		ISZERO: newStackOperation("ISZERO", 1, 1, 3, func(e *EVM) error { return e.IsZero() }),
+		AND:    newStackOperation("AND", 2, 1, 3, func(e *EVM) error { return e.And() }),
+		OR:     newStackOperation("OR", 2, 1, 3, func(e *EVM) error { return e.Or() }),
+		XOR:    newStackOperation("XOR", 2, 1, 3, func(e *EVM) error { return e.Xor() }),
+		NOT:    newStackOperation("NOT", 1, 1, 3, func(e *EVM) error { return e.Not() }),
+		BYTE:   newStackOperation("BYTE", 2, 1, 3, func(e *EVM) error { return e.Byte() }),
+
+		KECCAK256: newStackOperation("KECCAK256", 2, 1, 30, func(e *EVM) error { return e.Keccak256() }),
+
+		CALLVALUE:    newStackOperation("CALLVALUE", 0, 1, 2, func(e *EVM) error { return e.CallValue() }),
+		CALLDATALOAD: newStackOperation("CALLDATALOAD", 1, 1, 3, func(e *EVM) error { return e.CallDataLoad() }),
+		CALLDATASIZE: newStackOperation("CALLDATASIZE", 0, 1, 2, func(e *EVM) error { return e.CallDataSize() }),
+
+		POP:     newStackOperation("POP", 1, 0, 2, func(e *EVM) error { return e.Pop() }),
+		MLOAD:   newStackOperation("MLOAD", 1, 1, 3, func(e *EVM) error { return e.MLoad() }),
+		MSTORE:  newStackOperation("MSTORE", 2, 0, 3, func(e *EVM) error { return e.MStore() }),
+		MSTORE8: newStackOperation("MSTORE8", 2, 0, 3, func(e *EVM) error { return e.MStore8() }),
+
+		JUMP:     newStackOperation("JUMP", 1, 0, 8, func(e *EVM) error { return e.Jump() }),
+		JUMPI:    newStackOperation("JUMPI", 2, 0, 10, func(e *EVM) error { return e.JumpI() }),
+		JUMPDEST: newStackOperation("JUMPDEST", 0, 0, 1, func(e *EVM) error { return e.JumpDest() }),
+
+		RETURN: newStackOperation("RETURN", 2, 0, 0, func(e *EVM) error { return e.Return() }),
+	}
+
+	// EXP costs 50 gas per byte of the exponent (EIP-160).
+	set[EXP].DynamicGas = func(ctx *OperationContext) (uint64, error) {
+		exponent, err := ctx.Stack.Get(2)
+		if err != nil {
+			return 0, err
+		}
+		return 50 * uint64(exponent.ByteLen()), nil
+	}
+
+	// KECCAK256 costs 6 gas per word of data hashed.
+	set[KECCAK256].DynamicGas = func(ctx *OperationContext) (uint64, error) {
+		size, err := ctx.Stack.Get(2)
+		if err != nil {
+			return 0, err
+		}
+		if !size.IsUint64() {
+			return 0, ErrOutOfGas
+		}
+		return saturatingMul(toWordSize(size.Uint64()), 6), nil
+	}
+	set[KECCAK256].MemorySize = memorySizeFromStack(1, 2, 0)
+
+	// Memory operations expand the memory up to the accessed offset.
+	set[MLOAD].MemorySize = memorySizeFromStack(1, 0, 32)
+	set[MSTORE].MemorySize = memorySizeFromStack(1, 0, 32)
+	set[MSTORE8].MemorySize = memorySizeFromStack(1, 0, 1)
+
+	// RETURN copies its data from memory.
+	set[RETURN].MemorySize = memorySizeFromStack(1, 2, 0)
+	set[RETURN].Halts = true
+
+	// Stack operations.
+	for i := 0; i < 32; i++ {
+		n := i + 1
+		set[PUSH1+OpCode(i)] = &Operation{
+			Name:          fmt.Sprintf("PUSH%d", n),
+			StackOut:      1,
+			ImmediateSize: n,
+			ConstantGas:   3,
+			Execute:       func(ctx *OperationContext) error { return ctx.evm.pushN(n) },
+		}
+	}
+	for i := 0; i < 16; i++ {
+		n := i + 1
+		set[DUP1+OpCode(i)] = newStackOperation(fmt.Sprintf("DUP%d", n), n, n+1, 3, func(e *EVM) error { return e.dupN(n) })
+		set[SWAP1+OpCode(i)] = newStackOperation(fmt.Sprintf("SWAP%d", n), n+1, n+1, 3, func(e *EVM) error { return e.swapN(n + 1) })
+	}
+
+	// LOG operations cost 375 gas per topic and 8 gas per byte of data.
+	for i := 0; i <= 4; i++ {
+		n := i
+		set[LOG0+OpCode(i)] = newStackOperation(fmt.Sprintf("LOG%d", n), n+2, 0, 375, func(e *EVM) error { return e.logN(n) })
+		set[LOG0+OpCode(i)].DynamicGas = func(ctx *OperationContext) (uint64, error) {
+			size, err := ctx.Stack.Get(2)
+			if err != nil {
+				return 0, err
+			}
+			if !size.IsUint64() {
+				return 0, ErrOutOfGas
+			}
+			return saturatingMul(size.Uint64(), 8) + 375*uint64(n), nil
+		}
+		set[LOG0+OpCode(i)].MemorySize = memorySizeFromStack(1, 2, 0)
+	}
+
+	// Instructions introduced by network upgrades.
+	if fork >= Byzantium {
+		set[REVERT] = newStackOperation("REVERT", 2, 0, 0, func(e *EVM) error { return e.Revert() })
+		set[REVERT].MemorySize = memorySizeFromStack(1, 2, 0)
+		set[REVERT].Halts = true
+	}
+	if fork >= Constantinople {
+		set[SHL] = newStackOperation("SHL", 2, 1, 3, func(e *EVM) error { return e.Shl() })
+		set[SHR] = newStackOperation("SHR", 2, 1, 3, func(e *EVM) error { return e.Shr() })
+		set[SAR] = newStackOperation("SAR", 2, 1, 3, func(e *EVM) error { return e.Sar() })
+	}
+	if fork >= Shanghai {
+		set[PUSH0] = newStackOperation("PUSH0", 0, 1, 2, func(e *EVM) error { return e.Push0() })
+	}
+	if fork >= Cancun {
+		set[BLOBHASH] = newStackOperation("BLOBHASH", 1, 1, 3, func(e *EVM) error { return e.BlobHash() })
+		set[BLOBBASEFEE] = newStackOperation("BLOBBASEFEE", 0, 1, 2, func(e *EVM) error { return e.BlobBaseFee() })
+	}
+	return set
+}
+
+// Register adds a custom operation for an unused opcode.
+// It returns ErrOpcodeAlreadyDefined if the opcode is already used, and ErrInvalidOperation if the operation has no name or no execute function.
+func (s *InstructionSet) Register(opcode OpCode, op *Operation) error {
+	if op == nil || op.Name == "" || op.Execute == nil {
+		return ErrInvalidOperation
+	}
+	if s[opcode] != nil {
+		return fmt.Errorf("%w: %#02x (%s)", ErrOpcodeAlreadyDefined, byte(opcode), s[opcode].Name)
+	}
+	s[opcode] = op
+	return nil
+}
+
+// Name returns the name of the instruction mapped to the opcode.
+// Unused opcodes are named like go-ethereum does (e.g. "opcode 0xef not defined"), and 0xfe is named INVALID.
+func (s *InstructionSet) Name(opcode OpCode) string {
+	if op := s[opcode]; op != nil {
+		return op.Name
+	}
+	if opcode == INVALID {
+		return "INVALID"
+	}
+	return fmt.Sprintf("opcode %#x not defined", int(opcode))
+}
+
+// Return the operation mapped to the opcode.
+// Unused opcodes are mapped to an operation which fails with ErrInvalidOpcode.
+func (s *InstructionSet) operation(opcode OpCode) *Operation {
+	if op := s[opcode]; op != nil {
+		return op
+	}
+	return &Operation{
+		Name:    s.Name(opcode),
+		Execute: func(ctx *OperationContext) error { return ErrInvalidOpcode },
+	}
+}
+
+// Create an operation which relies on one of the EVM methods.
+func newStackOperation(name string, stackIn, stackOut int, gas uint64, method func(e *EVM) error) *Operation {
+	return &Operation{
+		Name:        name,
+		StackIn:     stackIn,
+		StackOut:    stackOut,
+		ConstantGas: gas,
+		Execute:     func(ctx *OperationContext) error { return method(ctx.evm) },
+	}
+}
+
+// Create a memory size function for instructions accessing the memory region [offset, offset+size).
+// The offset is the i-th stack item. The size is either the j-th stack item, or the given fixed size if j is zero.
+// Accessing an empty region does not expand the memory.
+func memorySizeFromStack(i, j int, fixedSize uint64) func(stack IStack) (uint64, bool) {
+	return func(stack IStack) (uint64, bool) {
+		size := uint256.NewInt(fixedSize)
+		if j > 0 {
+			var err error
+			if size, err = stack.Get(j); err != nil {
+				return 0, false
+			}
+		}
+		if size.IsZero() {
+			return 0, false
+		}
+		offset, err := stack.Get(i)
+		if err != nil {
+			return 0, false
+		}
+		end, overflow := new(uint256.Int).AddOverflow(offset, size)
+		if overflow || !end.IsUint64() {
+			return 0, true
+		}
+		return end.Uint64(), false
+	}
+}
@@ -0,0 +1,118 @@
+package evm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/holiman/uint256"
+)
+
+func TestNewInstructionSet(t *testing.T) {
+	set := NewInstructionSet(LatestFork)
+	tests := []struct {
+		opcode OpCode
+		name   string
+	}{
+		{STOP, "STOP"},
+		{ADD, "ADD"},
+		{KECCAK256, "KECCAK256"},
+		{PUSH0, "PUSH0"},
+		{PUSH1, "PUSH1"},
+		{PUSH32, "PUSH32"},
+		{DUP16, "DUP16"},
+		{SWAP1, "SWAP1"},
+		{INVALID, "INVALID"},
+		{0xef, "opcode 0xef not defined"},
+		{0x0c, "opcode 0xc not defined"},
+	}
+	for _, test := range tests {
+		if name := set.Name(test.opcode); name != test.name {
+			t.Errorf("Name(%#02x) returned %s, wanted %s", byte(test.opcode), name, test.name)
+		}
+	}
+	if size := set[PUSH32].ImmediateSize; size != 32 {
+		t.Errorf("PUSH32 should have a 32-byte immediate, got %d", size)
+	}
+}
+
+func TestInstructionSetFollowsFork(t *testing.T) {
+	tests := []struct {
+		opcode OpCode
+		fork   Fork
+	}{
+		{SHL, Constantinople},
+		{PUSH0, Shanghai},
+		{BLOBHASH, Cancun},
+	}
+	for _, test := range tests {
+		if op := NewInstructionSet(test.fork - 1)[test.opcode]; op != nil {
+			t.Errorf("%s should not be active before %s", op.Name, test.fork)
+		}
+		if op := NewInstructionSet(test.fork)[test.opcode]; op == nil {
+			t.Errorf("Opcode %#02x should be active from %s", byte(test.opcode), test.fork)
+		}
+	}
+}
+
+func TestStandardName(t *testing.T) {
+	tests := []struct {
+		opcode OpCode
+		name   string
+	}{
+		{JUMPI, "JUMPI"},
+		{PUSH32, "PUSH32"},
+		{LOG4, "LOG4"},
+		{SELFDESTRUCT, "SELFDESTRUCT"},
+	}
+	for _, test := range tests {
+		if name, ok := StandardName(test.opcode); !ok || name != test.name {
+			t.Errorf("StandardName(%#02x) returned %s, wanted %s", byte(test.opcode), name, test.name)
+		}
+		if opcode, ok := LookupOpCode(test.name); !ok || opcode != test.opcode {
+			t.Errorf("LookupOpCode(%s) returned %#02x, wanted %#02x", test.name, byte(opcode), byte(test.opcode))
+		}
+	}
+	if _, ok := StandardName(0x0c); ok {
+		t.Errorf("StandardName() should not name an undefined opcode")
+	}
+	if opcode, ok := LookupOpCode("sha3"); !ok || opcode != KECCAK256 {
+		t.Errorf("LookupOpCode() should accept SHA3 as an alias of KECCAK256")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	set := NewInstructionSet(LatestFork)
+	op := &Operation{Name: "NOOP", Execute: func(ctx *OperationContext) error { return nil }}
+	if err := set.Register(0xef, op); err != nil {
+		t.Fatalf("Register() returned an unexpected error: %v", err)
+	}
+	if name := set.Name(0xef); name != "NOOP" {
+		t.Errorf("Name() returned %s, wanted NOOP", name)
+	}
+
+	if err := set.Register(ADD, op); !errors.Is(err, ErrOpcodeAlreadyDefined) {
+		t.Errorf("Register() returned an unexpected error: %v, wanted: %v", err, ErrOpcodeAlreadyDefined)
+	}
+	if err := set.Register(0xee, &Operation{Name: "NOOP"}); err != ErrInvalidOperation {
+		t.Errorf("Register() returned an unexpected error: %v, wanted: %v", err, ErrInvalidOperation)
+	}
+}
+
+func TestMemorySizeFromStack(t *testing.T) {
+	stack := NewStack()
+	stack.Push(uint256.NewInt(10)) // size
+	stack.Push(uint256.NewInt(64)) // offset
+
+	if size, overflow := memorySizeFromStack(1, 2, 0)(stack); overflow || size != 74 {
+		t.Errorf("Expected memory size 74, got %d (overflow: %v)", size, overflow)
+	}
+	if size, overflow := memorySizeFromStack(1, 0, 32)(stack); overflow || size != 96 {
+		t.Errorf("Expected memory size 96, got %d (overflow: %v)", size, overflow)
+	}
+
+	// An offset which doesn't fit in 64 bits overflows.
+	stack.Push(new(uint256.Int).Lsh(uint256.NewInt(1), 255))
+	if _, overflow := memorySizeFromStack(1, 0, 32)(stack); !overflow {
+		t.Errorf("Expected memory size to overflow")
+	}
+}
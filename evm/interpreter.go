@@ -0,0 +1,381 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+)
+
+// DefaultGasLimit is the amount of gas available to the execution when none is provided.
+const DefaultGasLimit uint64 = 30_000_000
+
+// maxMemorySize is the largest memory size, in bytes, whose expansion cost fits in 64 bits.
+// Larger memory accesses always run out of gas.
+const maxMemorySize uint64 = 0x1FFFFFFFE0
+
+// contextCheckInterval is the number of instructions executed between two checks of the context.
+const contextCheckInterval = 1024
+
+var (
+	// ErrInvalidOpcode is returned when the code contains an opcode which is not defined in the instruction set.
+	ErrInvalidOpcode = errors.New("invalid opcode")
+	// ErrExecutionCancelled is returned when the context of the execution is cancelled or expires.
+	ErrExecutionCancelled = errors.New("execution cancelled")
+	// ErrStepLimit is returned when the execution reaches the maximum number of instructions.
+	ErrStepLimit = errors.New("step limit reached")
+	// ErrMemoryLimit is returned when an instruction expands the memory beyond the maximum size.
+	ErrMemoryLimit = errors.New("memory limit reached")
+)
+
+// IInterpreter defines the methods to execute the code of an EVM instance.
+type IInterpreter interface {
+	// Run executes the code from the current program counter until it halts, and returns the result of the execution.
+	// Running past the end of the code halts the execution like STOP.
+	// If an instruction fails, e.g. an invalid opcode or a lack of gas, the error is reported in the result.
+	Run() *ExecutionResult
+
+	// RunContext executes the code like Run, and halts with ErrExecutionCancelled once the context is done.
+	// The context is checked periodically, not before every instruction.
+	RunContext(ctx context.Context) *ExecutionResult
+
+	// RegisterOperation registers a custom operation for an unused opcode.
+	// The registration only applies to this EVM instance.
+	// It returns ErrOpcodeAlreadyDefined if the opcode is already used.
+	RegisterOperation(opcode OpCode, op *Operation) error
+
+	// Instructions returns the instruction set executed by the interpreter, including custom operations.
+	Instructions() *InstructionSet
+}
+
+// OperationContext gives an operation access to the state of the EVM executing it.
+type OperationContext struct {
+	// Stack of the EVM.
+	Stack IStack
+
+	// Memory of the EVM.
+	// It is already expanded to the size returned by the MemorySize function of the operation.
+	Memory IMemory
+
+	// Storage of the account executing the code.
+	Storage IStorage
+
+	evm           *EVM
+	jumped        bool
+	immediateSize int
+}
+
+// PC returns the program counter, i.e. the position of the instruction in the code.
+func (c *OperationContext) PC() int {
+	return c.evm.state.pc
+}
+
+// SetPC moves the program counter to the given position.
+// The interpreter doesn't move to the next instruction after the operation.
+func (c *OperationContext) SetPC(pc int) {
+	c.evm.state.pc = pc
+	c.jumped = true
+}
+
+// Code returns the code being executed.
+func (c *OperationContext) Code() []byte {
+	return c.evm.env.code
+}
+
+// Immediate returns the data following the opcode in the code, whose size is the ImmediateSize of the operation.
+// It is shorter if the code ends in the middle of the data.
+func (c *OperationContext) Immediate() []byte {
+	code, pc := c.evm.env.code, c.evm.state.pc
+	start, end := min(pc+1, len(code)), min(pc+1+c.immediateSize, len(code))
+	return append([]byte{}, code[start:end]...)
+}
+
+// Gas returns the amount of gas left, once the cost of the operation is charged.
+func (c *OperationContext) Gas() uint64 {
+	return c.evm.state.gas
+}
+
+// AddRefund adds gas to refund at the end of the execution.
+func (c *OperationContext) AddRefund(gas uint64) {
+	c.evm.state.refund += gas
+}
+
+// Input returns the input data of the call.
+func (c *OperationContext) Input() []byte {
+	return c.evm.env.input
+}
+
+// ReportBalanceChange reports to the tracer a change to the balance of an account made by the operation.
+// The EVM doesn't track balances, so operations modelling them are responsible for reporting their changes.
+func (c *OperationContext) ReportBalanceChange(address common.Address, prev, value *uint256.Int) {
+	if c.evm.tracer != nil {
+		c.evm.tracer.OnBalanceChange(address, new(uint256.Int).Set(prev), new(uint256.Int).Set(value))
+	}
+}
+
+// Fork returns the network upgrade whose rules apply to the execution.
+func (c *OperationContext) Fork() Fork {
+	return c.evm.env.fork
+}
+
+func (e *EVM) RegisterOperation(opcode OpCode, op *Operation) error {
+	if err := e.instructions.Register(opcode, op); err != nil {
+		return err
+	}
+	// The operation may have immediate data, which isn't a valid jump destination.
+	e.jumpdests = nil
+	return nil
+}
+
+func (e *EVM) Instructions() *InstructionSet {
+	return e.instructions
+}
+
+func (e *EVM) Run() *ExecutionResult {
+	return e.RunContext(context.Background())
+}
+
+func (e *EVM) RunContext(ctx context.Context) *ExecutionResult {
+	for i := 0; ; i++ {
+		if i%contextCheckInterval == 0 && !e.state.halted {
+			if err := ctx.Err(); err != nil {
+				e.halt(fmt.Errorf("%w: %w", ErrExecutionCancelled, err))
+				return e.result()
+			}
+		}
+		if halted, _ := e.step(); halted {
+			return e.result()
+		}
+	}
+}
+
+// stepContext describes the instruction being executed.
+type stepContext struct {
+	pc        int
+	opcode    OpCode
+	op        *Operation
+	gas       uint64
+	cost      uint64
+	stackSize int
+
+	// True once the instruction was reported to the tracer.
+	traced bool
+}
+
+// Execute the instruction at the current program counter.
+// It returns true if the execution halted, along with the error which halted it, if any.
+// Errors, except ErrExecutionReverted, are wrapped in a VMError describing the state of the EVM before the instruction.
+// Once the execution halted, it returns the same error without executing anything.
+func (e *EVM) step() (bool, error) {
+	if e.state.halted {
+		return true, e.state.err
+	}
+	if err := e.safeTrace(e.enter); err != nil {
+		e.halt(err)
+		return true, e.state.err
+	}
+
+	// Running past the end of the code executes STOP.
+	s := &stepContext{pc: e.state.pc, opcode: STOP, gas: e.state.gas, stackSize: e.stack.Size()}
+	if s.pc < len(e.env.code) {
+		s.opcode = OpCode(e.env.code[s.pc])
+	}
+	s.op = e.instructions.operation(s.opcode)
+
+	halted, err := e.safeExecute(s)
+	if err == nil && e.env.paranoid {
+		err = e.checkInvariants(s.op, s.stackSize)
+	}
+	err = e.wrapError(s, err)
+
+	// Report the error to the tracer, as a fault if the instruction was already reported.
+	if err != nil && e.tracer != nil {
+		traceErr := e.safeTrace(func() {
+			if s.traced {
+				e.tracer.OnFault(s.pc, s.opcode, s.gas, s.cost, e.scope(), e.env.depth, err)
+			} else {
+				e.tracer.OnOpcode(s.pc, s.opcode, s.gas, s.cost, e.scope(), e.state.returnData, e.env.depth, err)
+			}
+		})
+		if traceErr != nil {
+			err = e.wrapError(s, traceErr)
+		}
+	}
+	if halted || err != nil {
+		e.halt(err)
+		return true, e.state.err
+	}
+	return false, nil
+}
+
+// Wrap an error, except ErrExecutionReverted, in a VMError describing the state of the EVM before the instruction.
+func (e *EVM) wrapError(s *stepContext, err error) error {
+	if err == nil || errors.Is(err, ErrExecutionReverted) {
+		return err
+	}
+	return &VMError{
+		Err:           err,
+		PC:            s.pc,
+		OpCode:        s.opcode,
+		OpName:        s.op.Name,
+		Depth:         e.env.depth,
+		StackRequired: s.op.StackIn,
+		StackLimit:    MAX_STACK_SIZE + s.op.StackIn - s.op.StackOut,
+		StackSize:     s.stackSize,
+		Gas:           s.gas,
+	}
+}
+
+// Halt the execution with the given error, if any.
+// An exceptional halt consumes all the gas and returns no data.
+// If the tracer panics, the execution halts with ErrInternal instead.
+func (e *EVM) halt(err error) {
+	reverted := errors.Is(err, ErrExecutionReverted)
+	if err != nil && !reverted {
+		e.state.gas = 0
+		e.state.returnData = nil
+	}
+	e.state.halted, e.state.err = true, err
+	traceErr := e.safeTrace(func() {
+		e.enter()
+		if e.tracer != nil {
+			e.tracer.OnExit(e.env.depth, e.state.returnData, e.env.gas-e.state.gas, err, reverted)
+		}
+	})
+	if traceErr != nil {
+		e.state.gas, e.state.returnData, e.state.err = 0, nil, traceErr
+	}
+}
+
+// Call the tracer, and convert its panics into ErrInternal so they don't crash the caller.
+func (e *EVM) safeTrace(f func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrInternal, r)
+		}
+	}()
+	f()
+	return nil
+}
+
+// Execute an instruction like execute, and convert panics into ErrInternal so they don't crash the caller.
+func (e *EVM) safeExecute(s *stepContext) (halted bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			halted, err = true, fmt.Errorf("%w: %v", ErrInternal, r)
+		}
+	}()
+	return e.execute(s)
+}
+
+// Execute an instruction at the current program counter.
+// It returns true if the instruction halted the execution.
+// The gas is charged in the same order as go-ethereum, so the tracers report the same costs on failure.
+func (e *EVM) execute(s *stepContext) (bool, error) {
+	op := s.op
+	if e.env.maxSteps > 0 && e.state.steps >= e.env.maxSteps {
+		return true, ErrStepLimit
+	}
+	e.state.steps++
+	s.cost = op.ConstantGas
+
+	// Check the stack before executing the operation.
+	if size := e.stack.Size(); size < op.StackIn {
+		return true, ErrStackUnderflow
+	} else if size-op.StackIn+op.StackOut > MAX_STACK_SIZE {
+		return true, ErrStackOverflow
+	}
+
+	// Charge the static gas.
+	if err := e.useGas(op.ConstantGas); err != nil {
+		return true, err
+	}
+
+	// Compute the new size of the memory, rounded up to a multiple of 32 bytes.
+	var memorySize uint64
+	if op.MemorySize != nil {
+		size, overflow := op.MemorySize(e.stack)
+		if overflow || size > maxMemorySize {
+			return true, ErrOutOfGas
+		}
+		memorySize = toWordSize(size) * 32
+		if e.env.maxMemory > 0 && memorySize > e.env.maxMemory {
+			return true, ErrMemoryLimit
+		}
+	}
+
+	// Charge the dynamic gas, including the memory expansion.
+	ctx := &OperationContext{Stack: e.stack, Memory: e.memory, Storage: e.storage, evm: e, immediateSize: op.ImmediateSize}
+	if e.tracer != nil {
+		ctx.Storage = &tracingStorage{IStorage: e.storage, evm: e}
+	}
+	var dynamicGas uint64
+	if op.DynamicGas != nil {
+		gas, err := op.DynamicGas(ctx)
+		if err != nil {
+			return true, err
+		}
+		dynamicGas = gas
+	}
+	if currentSize := uint64(e.memory.Size()); memorySize > currentSize {
+		dynamicGas = saturatingAdd(dynamicGas, MemoryGasCost(memorySize)-MemoryGasCost(currentSize))
+	}
+	s.cost = saturatingAdd(s.cost, dynamicGas)
+	if err := e.useGas(dynamicGas); err != nil {
+		return true, err
+	}
+
+	// Report the instruction to the tracer before expanding the memory.
+	if e.tracer != nil {
+		e.tracer.OnOpcode(s.pc, s.opcode, s.gas, s.cost, e.scope(), e.state.returnData, e.env.depth, nil)
+		s.traced = true
+	}
+	e.memory.Resize(int(memorySize))
+
+	// Execute the operation and move to the next instruction, past its immediate data, unless the operation halted or moved the program counter.
+	if err := op.Execute(ctx); err != nil {
+		return true, err
+	}
+	if !op.Halts && !ctx.jumped && e.state.pc == s.pc {
+		e.state.pc += 1 + op.ImmediateSize
+	}
+	if e.tracer != nil {
+		e.tracer.OnOpcodeEnd(s.pc, s.opcode, e.state.gas, e.scope(), e.env.depth)
+	}
+	return op.Halts, nil
+}
+
+// Subtract the gas from the gas left.
+func (e *EVM) useGas(gas uint64) error {
+	if e.state.gas < gas {
+		return ErrOutOfGas
+	}
+	e.state.gas -= gas
+	return nil
+}
+
+// Add two amounts of gas, saturating at the maximum value instead of overflowing.
+func saturatingAdd(x, y uint64) uint64 {
+	if x > ^uint64(0)-y {
+		return ^uint64(0)
+	}
+	return x + y
+}
+
+// MemoryGasCost returns the cost of a memory of the given size, in bytes.
+// The cost of a memory expansion is the difference between the costs of the new and the current sizes.
+// The size should be a multiple of 32 bytes and smaller than 0x1FFFFFFFE0.
+func MemoryGasCost(size uint64) uint64 {
+	words := size / 32
+	return words*3 + words*words/512
+}
+
+// Return the number of 32-byte words needed to hold the given number of bytes.
+func toWordSize(size uint64) uint64 {
+	if size > ^uint64(0)-31 {
+		return ^uint64(0)/32 + 1
+	}
+	return (size + 31) / 32
+}
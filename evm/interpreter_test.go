@@ -0,0 +1,228 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/holiman/uint256"
+)
+
+func TestRun(t *testing.T) {
+	// PUSH1 1, PUSH1 2, ADD, STOP
+	evm := NewEVM([]byte{0x60, 0x01, 0x60, 0x02, 0x01, 0x00}, WithGas(100))
+	if result := evm.Run(); result.Err != nil {
+		t.Fatalf("Run() returned an unexpected error: %v", result.Err)
+	}
+
+	e := evm.(*EVM)
+	if gas := e.state.gas; gas != 91 {
+		t.Errorf("Expected 91 gas left, got %d", gas)
+	}
+	if pc := e.state.pc; pc != 5 {
+		t.Errorf("Expected pc 5, got %d", pc)
+	}
+	if value, err := e.stack.Pop(); err != nil || value.Uint64() != 3 {
+		t.Errorf("Expected 3 on the stack, got %v (error: %v)", value, err)
+	}
+}
+
+func TestRunMemoryExpansion(t *testing.T) {
+	// PUSH1 0xff, PUSH1 0x20, MSTORE
+	evm := NewEVM([]byte{0x60, 0xff, 0x60, 0x20, 0x52}, WithGas(100))
+	if result := evm.Run(); result.Err != nil {
+		t.Fatalf("Run() returned an unexpected error: %v", result.Err)
+	}
+
+	// 3 + 3 gas for the pushes, 3 gas for MSTORE and 6 gas to expand the memory to 2 words.
+	e := evm.(*EVM)
+	if gas := e.state.gas; gas != 85 {
+		t.Errorf("Expected 85 gas left, got %d", gas)
+	}
+	if size := e.memory.Size(); size != 64 {
+		t.Errorf("Expected memory size 64, got %d", size)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		code        []byte
+		gas         uint64
+		expectedErr error
+	}{
+		{"invalid opcode", []byte{0xef}, 100, ErrInvalidOpcode},
+		{"stack underflow", []byte{0x60, 0x01, 0x01}, 100, ErrStackUnderflow},
+		{"out of gas", []byte{0x60, 0x01, 0x60, 0x02, 0x01}, 8, ErrOutOfGas},
+		{"memory out of gas", []byte{0x63, 0xff, 0xff, 0xff, 0xff, 0x51}, 100, ErrOutOfGas},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := NewEVM(test.code, WithGas(test.gas)).Run(); !errors.Is(result.Err, test.expectedErr) {
+				t.Errorf("Run() returned an unexpected error: %v, wanted: %v", result.Err, test.expectedErr)
+			}
+		})
+	}
+}
+
+func TestRunInstructionSetFollowsFork(t *testing.T) {
+	// PUSH0
+	if result := NewEVM([]byte{0x5f}, WithFork(London)).Run(); !errors.Is(result.Err, ErrInvalidOpcode) {
+		t.Errorf("Run() returned an unexpected error: %v, wanted: %v", result.Err, ErrInvalidOpcode)
+	}
+	if result := NewEVM([]byte{0x5f}, WithFork(Shanghai)).Run(); result.Err != nil {
+		t.Errorf("Run() returned an unexpected error: %v", result.Err)
+	}
+}
+
+func TestRegisterOperation(t *testing.T) {
+	// Define an opcode which doubles the top of the stack and writes it to memory.
+	double := &Operation{
+		Name:        "DOUBLE",
+		StackIn:     1,
+		StackOut:    1,
+		ConstantGas: 4,
+		MemorySize:  func(stack IStack) (uint64, bool) { return 32, false },
+		Execute: func(ctx *OperationContext) error {
+			x, err := ctx.Stack.Pop()
+			if err != nil {
+				return err
+			}
+			result := new(uint256.Int).Add(x, x)
+			ctx.Memory.StoreWord(result.Bytes32(), 0)
+			return ctx.Stack.Push(result)
+		},
+	}
+
+	// PUSH1 21, DOUBLE
+	evm := NewEVM([]byte{0x60, 0x15, 0xef}, WithGas(100))
+	if err := evm.RegisterOperation(0xef, double); err != nil {
+		t.Fatalf("RegisterOperation() returned an unexpected error: %v", err)
+	}
+	if result := evm.Run(); result.Err != nil {
+		t.Fatalf("Run() returned an unexpected error: %v", result.Err)
+	}
+
+	e := evm.(*EVM)
+	if value, err := e.stack.Pop(); err != nil || value.Uint64() != 42 {
+		t.Errorf("Expected 42 on the stack, got %v (error: %v)", value, err)
+	}
+	if word := e.memory.LoadWord(0); word[31] != 42 {
+		t.Errorf("Expected 42 in memory, got %x", word)
+	}
+	// 3 gas for the push, 4 gas for DOUBLE and 3 gas to expand the memory to 1 word.
+	if gas := e.state.gas; gas != 90 {
+		t.Errorf("Expected 90 gas left, got %d", gas)
+	}
+
+	// The registration should only apply to this instance.
+	if result := NewEVM([]byte{0x60, 0x15, 0xef}).Run(); !errors.Is(result.Err, ErrInvalidOpcode) {
+		t.Errorf("Run() returned an unexpected error: %v, wanted: %v", result.Err, ErrInvalidOpcode)
+	}
+}
+
+func TestRegisterOperationJump(t *testing.T) {
+	// Define an opcode which skips the next byte.
+	skip := &Operation{
+		Name:    "SKIP",
+		Execute: func(ctx *OperationContext) error { ctx.SetPC(ctx.PC() + 2); return nil },
+	}
+
+	// SKIP, INVALID, PUSH0
+	evm := NewEVM([]byte{0xef, 0xfe, 0x5f})
+	if err := evm.RegisterOperation(0xef, skip); err != nil {
+		t.Fatalf("RegisterOperation() returned an unexpected error: %v", err)
+	}
+	if result := evm.Run(); result.Err != nil {
+		t.Fatalf("Run() returned an unexpected error: %v", result.Err)
+	}
+	if size := evm.(*EVM).stack.Size(); size != 1 {
+		t.Errorf("Expected 1 item on the stack, got %d", size)
+	}
+}
+
+// Create an EVM with a custom opcode pushing the 2 bytes of immediate data following it.
+func newPushPairEVM(t *testing.T, code []byte) IEVM {
+	pushPair := &Operation{
+		Name:          "PUSHPAIR",
+		StackOut:      1,
+		ImmediateSize: 2,
+		ConstantGas:   3,
+		Execute: func(ctx *OperationContext) error {
+			return ctx.Stack.Push(new(uint256.Int).SetBytes(ctx.Immediate()))
+		},
+	}
+	evm := NewEVM(code)
+	if err := evm.RegisterOperation(0xef, pushPair); err != nil {
+		t.Fatalf("RegisterOperation() returned an unexpected error: %v", err)
+	}
+	return evm
+}
+
+func TestRegisterOperationImmediate(t *testing.T) {
+	// PUSHPAIR 0x0101, PUSHPAIR 0x01 (truncated): the immediate data is not executed as ADD.
+	code := []byte{0xef, 0x01, 0x01, 0xef, 0x01}
+	result := newPushPairEVM(t, code).Run()
+	if result.Err != nil {
+		t.Fatalf("Run() returned an unexpected error: %v", result.Err)
+	}
+	if len(result.Stack) != 2 || result.Stack[0].Uint64() != 0x0101 || result.Stack[1].Uint64() != 0x01 {
+		t.Errorf("Unexpected stack: %v", result.Stack)
+	}
+
+	// Stepping moves past the immediate data too.
+	evm := newPushPairEVM(t, code)
+	for _, pc := range []int{0, 3, 6} {
+		if step := evm.Step(); step.PC != pc {
+			t.Errorf("Step() executed the instruction at %d, wanted: %d", step.PC, pc)
+		}
+	}
+}
+
+// Create an EVM running an infinite loop, using a custom opcode which jumps back to the start of the code.
+func newLoopingEVM(t *testing.T, opts ...Option) IEVM {
+	loop := &Operation{
+		Name:        "LOOP",
+		ConstantGas: 1,
+		Execute:     func(ctx *OperationContext) error { ctx.SetPC(0); return nil },
+	}
+	evm := NewEVM([]byte{0xef}, opts...)
+	if err := evm.RegisterOperation(0xef, loop); err != nil {
+		t.Fatalf("RegisterOperation() returned an unexpected error: %v", err)
+	}
+	return evm
+}
+
+func TestRunContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	result := newLoopingEVM(t, WithGas(math.MaxUint64)).RunContext(ctx)
+	if !errors.Is(result.Err, ErrExecutionCancelled) || !errors.Is(result.Err, context.DeadlineExceeded) {
+		t.Errorf("RunContext() returned an unexpected error: %v, wanted: %v", result.Err, ErrExecutionCancelled)
+	}
+}
+
+func TestRunStepLimit(t *testing.T) {
+	evm := newLoopingEVM(t, WithMaxSteps(10))
+	result := evm.Run()
+	if !errors.Is(result.Err, ErrStepLimit) {
+		t.Errorf("Run() returned an unexpected error: %v, wanted: %v", result.Err, ErrStepLimit)
+	}
+	if steps := evm.(*EVM).state.steps; steps != 10 {
+		t.Errorf("Expected 10 instructions to be executed, got %d", steps)
+	}
+}
+
+func TestRunMemoryLimit(t *testing.T) {
+	// PUSH1 1, PUSH1 0x20, MSTORE
+	code := []byte{0x60, 0x01, 0x60, 0x20, 0x52}
+	if result := NewEVM(code, WithMaxMemory(64)).Run(); result.Err != nil {
+		t.Errorf("Run() returned an unexpected error: %v", result.Err)
+	}
+	if result := NewEVM(code, WithMaxMemory(63)).Run(); !errors.Is(result.Err, ErrMemoryLimit) {
+		t.Errorf("Run() returned an unexpected error: %v, wanted: %v", result.Err, ErrMemoryLimit)
+	}
+}
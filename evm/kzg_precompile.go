@@ -0,0 +1,75 @@
+package evm
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+)
+
+var (
+	// ErrPointEvaluationInvalidInputLength is returned when the point evaluation input is not exactly 192 bytes long.
+	ErrPointEvaluationInvalidInputLength = errors.New("invalid input length")
+	// ErrPointEvaluationMismatchedVersionedHash is returned when the commitment does not match the versioned hash.
+	ErrPointEvaluationMismatchedVersionedHash = errors.New("mismatched versioned hash")
+	// ErrPointEvaluationInvalidProof is returned when the KZG proof verification fails.
+	ErrPointEvaluationInvalidProof = errors.New("error verifying kzg proof")
+)
+
+const (
+	// Gas cost of the point evaluation precompile.
+	pointEvaluationGas uint64 = 50000
+	// Size of the point evaluation input, in bytes.
+	pointEvaluationInputSize = 192
+	// Version byte of versioned hashes derived from KZG commitments.
+	blobCommitmentVersionKZG byte = 0x01
+)
+
+// Output of the point evaluation precompile: FIELD_ELEMENTS_PER_BLOB and BLS_MODULUS (32 bytes each).
+var pointEvaluationOutput = common.FromHex("0x000000000000000000000000000000000000000000000000000000000000100073eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000001")
+
+// kzgPointEvaluation implements the KZG point evaluation precompile (0x0A), as defined in EIP-4844.
+// It verifies that the blob committed to by the KZG commitment evaluates to y at point z.
+// The trusted setup is embedded in the binary so the verification works offline.
+// Input: [versionedHash (32 bytes), z (32 bytes), y (32 bytes), commitment (48 bytes), proof (48 bytes)]
+// Output: [FIELD_ELEMENTS_PER_BLOB, BLS_MODULUS] (32 bytes each)
+type kzgPointEvaluation struct{}
+
+func (c *kzgPointEvaluation) RequiredGas(input []byte) uint64 {
+	return pointEvaluationGas
+}
+
+func (c *kzgPointEvaluation) Run(input []byte) ([]byte, error) {
+	if len(input) != pointEvaluationInputSize {
+		return nil, ErrPointEvaluationInvalidInputLength
+	}
+
+	var versionedHash common.Hash
+	copy(versionedHash[:], input[0:32])
+	var point kzg4844.Point
+	copy(point[:], input[32:64])
+	var claim kzg4844.Claim
+	copy(claim[:], input[64:96])
+	var commitment kzg4844.Commitment
+	copy(commitment[:], input[96:144])
+	var proof kzg4844.Proof
+	copy(proof[:], input[144:192])
+
+	if kzgToVersionedHash(commitment) != versionedHash {
+		return nil, ErrPointEvaluationMismatchedVersionedHash
+	}
+	if err := kzg4844.VerifyProof(commitment, point, claim, proof); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPointEvaluationInvalidProof, err)
+	}
+	return common.CopyBytes(pointEvaluationOutput), nil
+}
+
+// Compute the versioned hash of a KZG commitment.
+// It is the SHA-256 hash of the commitment where the first byte is replaced by the version byte.
+func kzgToVersionedHash(commitment kzg4844.Commitment) common.Hash {
+	hash := sha256.Sum256(commitment[:])
+	hash[0] = blobCommitmentVersionKZG
+	return hash
+}
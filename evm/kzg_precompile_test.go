@@ -0,0 +1,52 @@
+package evm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestPointEvaluation(t *testing.T) {
+	testPrecompileVectors(t, &kzgPointEvaluation{}, "pointEvaluation")
+}
+
+func TestPointEvaluationActivation(t *testing.T) {
+	address := common.BytesToAddress([]byte{0x0a})
+	if _, ok := PrecompiledContracts(Shanghai)[address]; ok {
+		t.Errorf("Point evaluation should not be active before Cancun")
+	}
+	if _, ok := PrecompiledContracts(Cancun)[address]; !ok {
+		t.Errorf("Point evaluation should be active from Cancun")
+	}
+}
+
+func TestPointEvaluationFailures(t *testing.T) {
+	// Valid input taken from the pointEvaluation test vector.
+	input := common.FromHex("01e798154708fe7789429634053cbf9f99b619f9f084048927333fce637f549b564c0a11a0f704f4fc3e8acfe0f8245f0ad1347b378fbf96e206da11a5d3630624d25032e67a7e6a4910df5834b8fe70e6bcfeeac0352434196bdf4b2485d5a18f59a8d2a1a625a17f3fea0fe5eb8c896db3764f3185481bc22f91b4aaffcca25f26936857bc3a7c2539ea8ec3a952b7873033e038326e87ed3e1276fd140253fa08e9fc25fb2d9a98527fc22a2c9612fbeafdad446cbc7bcdbdcd780af2c16a")
+
+	tamperedHash := common.CopyBytes(input)
+	tamperedHash[0] = 0x02
+	tamperedClaim := common.CopyBytes(input)
+	tamperedClaim[95] ^= 0x01
+
+	tests := []struct {
+		name        string
+		input       []byte
+		expectedErr error
+	}{
+		{"empty input", nil, ErrPointEvaluationInvalidInputLength},
+		{"truncated input", input[:191], ErrPointEvaluationInvalidInputLength},
+		{"mismatched versioned hash", tamperedHash, ErrPointEvaluationMismatchedVersionedHash},
+		{"invalid proof", tamperedClaim, ErrPointEvaluationInvalidProof},
+	}
+
+	p := &kzgPointEvaluation{}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := p.Run(test.input); !errors.Is(err, test.expectedErr) {
+				t.Errorf("Run() returned an unexpected error: %v, wanted: %v", err, test.expectedErr)
+			}
+		})
+	}
+}
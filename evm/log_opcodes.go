@@ -0,0 +1,105 @@
+package evm
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Log represents an event emitted by the LOG instructions.
+type Log struct {
+	// Address of the account which emitted the event.
+	Address common.Address
+
+	// Indexed topics of the event.
+	Topics []common.Hash
+
+	// Non-indexed data of the event.
+	Data []byte
+}
+
+// ILogOps defines operations which emit events.
+// All operations pop the offset and the size of the data in memory from the stack, followed by the topics.
+// All methods return an error if there are not enough elements on the stack.
+type ILogOps interface {
+	// Log0 emits an event without topics.
+	// Stack: [offset, size, ...] -> [...]
+	Log0() error
+
+	// Log1 emits an event with one topic.
+	// Stack: [offset, size, topic0, ...] -> [...]
+	Log1() error
+
+	// Log2 emits an event with two topics.
+	// Stack: [offset, size, topic0, topic1, ...] -> [...]
+	Log2() error
+
+	// Log3 emits an event with three topics.
+	// Stack: [offset, size, topic0, topic1, topic2, ...] -> [...]
+	Log3() error
+
+	// Log4 emits an event with four topics.
+	// Stack: [offset, size, topic0, topic1, topic2, topic3, ...] -> [...]
+	Log4() error
+}
+
+func (e *EVM) Log0() error {
+	return e.logN(0)
+}
+
+func (e *EVM) Log1() error {
+	return e.logN(1)
+}
+
+func (e *EVM) Log2() error {
+	return e.logN(2)
+}
+
+func (e *EVM) Log3() error {
+	return e.logN(3)
+}
+
+func (e *EVM) Log4() error {
+	return e.logN(4)
+}
+
+func (e *EVM) logN(n int) error {
+	if e.stack.Size() < n+2 {
+		return ErrStackUnderflow
+	}
+
+	// Load offset and size from the stack.
+	offset, err := e.stack.Pop()
+	if err != nil {
+		return err
+	}
+	size, err := e.stack.Pop()
+	if err != nil {
+		return err
+	}
+
+	// Load topics from the stack.
+	topics := make([]common.Hash, n)
+	for i := range topics {
+		topic, err := e.stack.Pop()
+		if err != nil {
+			return err
+		}
+		topics[i] = topic.Bytes32()
+	}
+
+	// Copy the data, as the memory may still be modified.
+	start, length, err := e.memoryRegion(offset, size)
+	if err != nil {
+		return err
+	}
+	data := e.memory.Load(start, length)
+	log := &Log{
+		Address: e.env.address,
+		Topics:  topics,
+		Data:    append([]byte{}, data...),
+	}
+	e.state.logs = append(e.state.logs, log)
+	if e.tracer != nil {
+		e.tracer.OnLog(log)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package evm
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestLog(t *testing.T) {
+	address := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	evm := NewEVM(nil, WithAddress(address))
+	op := func(evm IEVM) error { return evm.Log2() }
+	initialMemory := []byte{0x01, 0x02, 0x03, 0x04}
+	testStackOperationWithExistingEVM(t, evm, op, nil, []uint64{1, 0xbb, 0xaa, 2, 1}, []uint64{1}, initialMemory, initialMemory)
+
+	logs := evm.(*EVM).state.logs
+	if len(logs) != 1 {
+		t.Fatalf("Expected 1 log, got %d", len(logs))
+	}
+	if logs[0].Address != address {
+		t.Errorf("Expected address %v, got %v", address, logs[0].Address)
+	}
+	expectedTopics := []common.Hash{common.BytesToHash([]byte{0xaa}), common.BytesToHash([]byte{0xbb})}
+	if len(logs[0].Topics) != 2 || logs[0].Topics[0] != expectedTopics[0] || logs[0].Topics[1] != expectedTopics[1] {
+		t.Errorf("Expected topics %v, got %v", expectedTopics, logs[0].Topics)
+	}
+	if !bytes.Equal(logs[0].Data, []byte{0x02, 0x03}) {
+		t.Errorf("Expected data %x, got %x", []byte{0x02, 0x03}, logs[0].Data)
+	}
+}
+
+func TestLogOnEmptyStack(t *testing.T) {
+	op := func(evm IEVM) error { return evm.Log1() }
+	testStackOperationWithNewEVM(t, op, ErrStackUnderflow, []uint64{0, 0}, []uint64{0, 0}, nil, nil, nil)
+}
@@ -23,6 +23,13 @@ type IMemory interface {
 
 	// Store a word (32 bytes) to memory at the given offset.
 	StoreWord(word [32]byte, offset int)
+
+	// Size returns the current size of the memory, in bytes.
+	Size() int
+
+	// Resize expands the memory to the given size, in bytes.
+	// The new bytes are zero-initialized. The memory is never shrunk.
+	Resize(size int)
 }
 
 // Memory represents a byte-addressable memory structure.
@@ -83,3 +90,13 @@ func (m *Memory) StoreByte(value byte, offset int) {
 func (m *Memory) StoreWord(word [32]byte, offset int) {
 	m.Store(word[:], offset)
 }
+
+func (m *Memory) Size() int {
+	return len(m.data)
+}
+
+func (m *Memory) Resize(size int) {
+	if len(m.data) < size {
+		m.data = append(m.data, make([]byte, size-len(m.data))...)
+	}
+}
@@ -4,6 +4,11 @@ import (
 	"github.com/holiman/uint256"
 )
 
+var (
+	wordSize = uint256.NewInt(32)
+	byteSize = uint256.NewInt(1)
+)
+
 // IPushOps defines operations on the EVM memory.
 type IMemoryOps interface {
 	// MLoad loads a word from memory.
@@ -37,7 +42,11 @@ func (e *EVM) MLoad() error {
 	}
 
 	// Load memory from memory at given offset.
-	word := e.memory.LoadWord(int(offset.Uint64()))
+	start, _, err := e.memoryRegion(offset, wordSize)
+	if err != nil {
+		return err
+	}
+	word := e.memory.LoadWord(start)
 
 	// Store word at the top of the stack.
 	value := new(uint256.Int).SetBytes(word[:])
@@ -59,8 +68,11 @@ func (e *EVM) MStore() error {
 	}
 
 	// Store word at the given offset in memory.
-	word := value.Bytes32()
-	e.memory.StoreWord(word, int(offset.Uint64()))
+	start, _, err := e.memoryRegion(offset, wordSize)
+	if err != nil {
+		return err
+	}
+	e.memory.StoreWord(value.Bytes32(), start)
 	return nil
 }
 
@@ -78,9 +90,33 @@ func (e *EVM) MStore8() error {
 		return err
 	}
 
-	// Store byte at the given offset in memory.
-	word := value.Bytes()
-	// TODO: Should we check the size of word?
-	e.memory.StoreByte(word[0], int(offset.Uint64()))
+	// Store the least significant byte at the given offset in memory.
+	start, _, err := e.memoryRegion(offset, byteSize)
+	if err != nil {
+		return err
+	}
+	e.memory.StoreByte(byte(value.Uint64()), start)
 	return nil
 }
+
+// Convert the offset and the size of a memory region, taken from the stack, to integers.
+// It returns ErrOutOfGas if the region doesn't fit in 64 bits, or if the gas left can't pay for the memory expansion.
+// The memory is not expanded and the gas is not charged, the interpreter does it before executing the instruction.
+// An empty region never expands the memory, so its offset is ignored and zero is returned.
+func (e *EVM) memoryRegion(offset, size *uint256.Int) (int, int, error) {
+	if size.IsZero() {
+		return 0, 0, nil
+	}
+	end, overflow := new(uint256.Int).AddOverflow(offset, size)
+	if overflow || !end.IsUint64() || end.Uint64() > maxMemorySize {
+		return 0, 0, ErrOutOfGas
+	}
+
+	// Check that the memory expansion can be paid for.
+	if newSize, currentSize := toWordSize(end.Uint64())*32, uint64(e.memory.Size()); newSize > currentSize {
+		if MemoryGasCost(newSize)-MemoryGasCost(currentSize) > e.state.gas {
+			return 0, 0, ErrOutOfGas
+		}
+	}
+	return int(offset.Uint64()), int(size.Uint64()), nil
+}
@@ -1,6 +1,8 @@
 package evm
 
 import (
+	"errors"
+	"math"
 	"testing"
 
 	"github.com/holiman/uint256"
@@ -96,6 +98,12 @@ func TestMStore8(t *testing.T) {
 	testStackOperationWithNewEVM(t, op, nil, initialStack, expectedStack, initialMemory, expectedMemory, nil)
 }
 
+func TestMStore8StoresLeastSignificantByte(t *testing.T) {
+	op := func(evm IEVM) error { return evm.MStore8() }
+	testStackOperationWithNewEVM(t, op, nil, []uint64{0x1234, 0}, nil, nil, []byte{0x34}, nil)
+	testStackOperationWithNewEVM(t, op, nil, []uint64{0, 0}, nil, []byte{0xff}, []byte{0x00}, nil)
+}
+
 func TestMStore8OnEmptyStack(t *testing.T) {
 	op := func(evm IEVM) error { return evm.MStore8() }
 	testStackOperationWithNewEVM(t, op, ErrStackUnderflow, nil, nil, nil, nil, nil)
@@ -106,3 +114,47 @@ func TestMStore8OnOneElementStack(t *testing.T) {
 	initialStack := []uint64{1}
 	testStackOperationWithNewEVM(t, op, ErrStackUnderflow, initialStack, nil, nil, nil, nil)
 }
+
+func TestMemoryOperationsWithLargeOffsets(t *testing.T) {
+	ops := map[string]func(evm IEVM) error{
+		"MLoad":   func(evm IEVM) error { return evm.MLoad() },
+		"MStore":  func(evm IEVM) error { return evm.MStore() },
+		"MStore8": func(evm IEVM) error { return evm.MStore8() },
+	}
+	offsets := map[string]*uint256.Int{
+		"2^255":            new(uint256.Int).Lsh(uint256.NewInt(1), 255),
+		"2^64":             new(uint256.Int).Lsh(uint256.NewInt(1), 64),
+		"max uint64":       uint256.NewInt(math.MaxUint64),
+		"unaffordable gas": uint256.NewInt(1 << 32),
+	}
+	for opName, op := range ops {
+		for offsetName, offset := range offsets {
+			t.Run(opName+" at "+offsetName, func(t *testing.T) {
+				evm := NewEVM(nil)
+				testEvm := evm.(ExtendedEVM)
+				testEvm.HelperPush(uint256.NewInt(1))
+				testEvm.HelperPush(uint256.NewInt(1))
+				testEvm.HelperPush(offset)
+				if err := op(evm); err != ErrOutOfGas {
+					t.Errorf("Operation returned an unexpected error: %v, wanted: %v", err, ErrOutOfGas)
+				}
+				if size := evm.(*EVM).memory.Size(); size != 0 {
+					t.Errorf("Memory should not be expanded, got size %d", size)
+				}
+			})
+		}
+	}
+}
+
+func TestRunMemoryOperationsWithLargeOffsets(t *testing.T) {
+	// PUSH1 1, PUSH32 2^255, MSTORE
+	code := append([]byte{0x60, 0x01, 0x7f, 0x80}, make([]byte, 31)...)
+	code = append(code, 0x52)
+	result := NewEVM(code, WithGas(1000)).Run()
+	if !errors.Is(result.Err, ErrOutOfGas) {
+		t.Errorf("Run() returned an unexpected error: %v, wanted: %v", result.Err, ErrOutOfGas)
+	}
+	if result.GasUsed != 1000 {
+		t.Errorf("An out of gas halt should consume all the gas, got %d", result.GasUsed)
+	}
+}
@@ -338,3 +338,29 @@ func TestStoreWord(t *testing.T) {
 		t.Errorf("StoreWord() at offset 32*3 returned word %v, wanted %v", word3, expectedWord3)
 	}
 }
+
+func TestResize(t *testing.T) {
+	// Create an empty memory.
+	m := NewMemory()
+	if size := m.Size(); size != 0 {
+		t.Errorf("Size() returned %d for an empty memory, wanted 0", size)
+	}
+
+	// Expand the memory to 64 bytes.
+	m.Store([]byte{0x1}, 0)
+	m.Resize(64)
+	if size := m.Size(); size != 64 {
+		t.Errorf("Size() returned %d after Resize(64), wanted 64", size)
+	}
+
+	// The memory should never be shrunk.
+	m.Resize(32)
+	if size := m.Size(); size != 64 {
+		t.Errorf("Size() returned %d after Resize(32), wanted 64", size)
+	}
+
+	// The existing data should be preserved.
+	if value := m.LoadByte(0); value != 0x1 {
+		t.Errorf("LoadByte() at offset 0 returned byte %v, wanted %v", value, 0x1)
+	}
+}
@@ -0,0 +1,199 @@
+package evm
+
+import (
+	"math"
+	"math/big"
+	"math/bits"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+)
+
+// ModExpPricing defines the gas pricing rules of the MODEXP precompile.
+type ModExpPricing int
+
+const (
+	// EIP-198 pricing, introduced in Byzantium.
+	ModExpPricingEIP198 ModExpPricing = iota
+	// EIP-2565 pricing, introduced in Berlin.
+	ModExpPricingEIP2565
+	// EIP-7883 pricing, introduced in Osaka.
+	ModExpPricingEIP7883
+)
+
+// Return the MODEXP pricing rules active at the given fork.
+func modExpPricing(fork Fork) ModExpPricing {
+	switch {
+	case fork >= Osaka:
+		return ModExpPricingEIP7883
+	case fork >= Berlin:
+		return ModExpPricingEIP2565
+	default:
+		return ModExpPricingEIP198
+	}
+}
+
+// modExp implements the MODEXP precompile (0x05).
+// It computes the arbitrary-precision modular exponentiation base**exp % mod.
+// Input: [baseLen (32 bytes), expLen (32 bytes), modLen (32 bytes), base, exp, mod]
+// Output: base**exp % mod, left-padded to modLen bytes.
+type modExp struct {
+	pricing ModExpPricing
+}
+
+// NewModExp creates and returns a new MODEXP precompile using the given pricing rules.
+func NewModExp(pricing ModExpPricing) IPrecompile {
+	return &modExp{pricing: pricing}
+}
+
+func (c *modExp) RequiredGas(input []byte) uint64 {
+	baseLen, expLen, modLen := modExpLengths(input)
+
+	// Read the first 32 bytes of the exponent, they are used to compute the number of iterations.
+	var expHead uint256.Int
+	if len(input) > 96 {
+		data := input[96:]
+		if uint64(len(data)) > baseLen {
+			expHead.SetBytes(getData(data, baseLen, min(expLen, 32)))
+		}
+	}
+
+	maxLen := max(baseLen, modLen)
+	switch c.pricing {
+	case ModExpPricingEIP7883:
+		// gas = max(500, multComplexity * iterationCount)
+		complexity := modExpMultComplexityEIP7883(maxLen)
+		iterations := modExpIterationCount(expLen, &expHead, 16)
+		return max(saturatingMul(complexity, iterations), 500)
+	case ModExpPricingEIP2565:
+		// gas = max(200, multComplexity * iterationCount / 3)
+		complexity := modExpMultComplexityEIP2565(maxLen)
+		iterations := modExpIterationCount(expLen, &expHead, 8)
+		return max(saturatingMulDiv(complexity, iterations, 3), 200)
+	default:
+		// gas = multComplexity * iterationCount / 20
+		complexity := modExpMultComplexityEIP198(maxLen)
+		iterations := modExpIterationCount(expLen, &expHead, 8)
+		return saturatingMulDiv(complexity, iterations, 20)
+	}
+}
+
+func (c *modExp) Run(input []byte) ([]byte, error) {
+	baseLen, expLen, modLen := modExpLengths(input)
+
+	// Handle the special case where both the base and the modulus are empty.
+	if baseLen == 0 && modLen == 0 {
+		return []byte{}, nil
+	}
+
+	var data []byte
+	if len(input) > 96 {
+		data = input[96:]
+	}
+	base := new(big.Int).SetBytes(getData(data, 0, baseLen))
+	exp := new(big.Int).SetBytes(getData(data, baseLen, expLen))
+	mod := new(big.Int).SetBytes(getData(data, baseLen+expLen, modLen))
+
+	// A zero modulus is undefined, return zero.
+	if mod.Sign() == 0 {
+		return make([]byte, modLen), nil
+	}
+	result := new(big.Int).Exp(base, exp, mod)
+	return common.LeftPadBytes(result.Bytes(), int(modLen)), nil
+}
+
+// Parse the base, exponent and modulus lengths from the MODEXP input.
+// Lengths that don't fit in 64 bits are capped to math.MaxUint64.
+func modExpLengths(input []byte) (baseLen, expLen, modLen uint64) {
+	toUint64 := func(b []byte) uint64 {
+		value := new(uint256.Int).SetBytes(b)
+		if !value.IsUint64() {
+			return math.MaxUint64
+		}
+		return value.Uint64()
+	}
+	baseLen = toUint64(getData(input, 0, 32))
+	expLen = toUint64(getData(input, 32, 32))
+	modLen = toUint64(getData(input, 64, 32))
+	return baseLen, expLen, modLen
+}
+
+// Compute the multiplication complexity as defined in EIP-198.
+// x <= 64: x**2
+// x <= 1024: x**2 / 4 + 96 * x - 3072
+// x > 1024: x**2 / 16 + 480 * x - 199680
+func modExpMultComplexityEIP198(x uint64) uint64 {
+	switch {
+	case x <= 64:
+		return x * x
+	case x <= 1024:
+		return x*x/4 + 96*x - 3072
+	default:
+		hi, square := bits.Mul64(x, x)
+		if hi != 0 {
+			return math.MaxUint64
+		}
+		sum, carry := bits.Add64(square/16, 480*x-199680, 0)
+		if carry != 0 {
+			return math.MaxUint64
+		}
+		return sum
+	}
+}
+
+// Compute the multiplication complexity as defined in EIP-2565.
+// ceil(x / 8)**2
+func modExpMultComplexityEIP2565(x uint64) uint64 {
+	words := x / 8
+	if x%8 != 0 {
+		words++
+	}
+	return saturatingMul(words, words)
+}
+
+// Compute the multiplication complexity as defined in EIP-7883.
+// x <= 32: 16
+// x > 32: 2 * ceil(x / 8)**2
+func modExpMultComplexityEIP7883(x uint64) uint64 {
+	if x <= 32 {
+		return 16
+	}
+	return saturatingMul(modExpMultComplexityEIP2565(x), 2)
+}
+
+// Compute the number of iterations of the exponentiation, also called the adjusted exponent length.
+// It is based on the bit length of the first 32 bytes of the exponent, and on the length of the rest of the exponent.
+// The result is at least one.
+func modExpIterationCount(expLen uint64, expHead *uint256.Int, multiplier uint64) uint64 {
+	var count uint64
+	if expLen > 32 {
+		count = saturatingMul(expLen-32, multiplier)
+	}
+	if bitLen := expHead.BitLen(); bitLen > 0 {
+		sum, carry := bits.Add64(count, uint64(bitLen-1), 0)
+		if carry != 0 {
+			return math.MaxUint64
+		}
+		count = sum
+	}
+	return max(count, 1)
+}
+
+// Multiply two numbers, capping the result to math.MaxUint64 on overflow.
+func saturatingMul(x, y uint64) uint64 {
+	hi, lo := bits.Mul64(x, y)
+	if hi != 0 {
+		return math.MaxUint64
+	}
+	return lo
+}
+
+// Multiply two numbers and divide the result, capping the result to math.MaxUint64 on overflow.
+func saturatingMulDiv(x, y, divisor uint64) uint64 {
+	hi, lo := bits.Mul64(x, y)
+	if hi >= divisor {
+		return math.MaxUint64
+	}
+	quotient, _ := bits.Div64(hi, lo, divisor)
+	return quotient
+}
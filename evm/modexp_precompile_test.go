@@ -0,0 +1,75 @@
+package evm
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestModExpEIP198(t *testing.T) {
+	testPrecompileVectors(t, NewModExp(ModExpPricingEIP198), "modexp")
+}
+
+func TestModExpEIP2565(t *testing.T) {
+	testPrecompileVectors(t, NewModExp(ModExpPricingEIP2565), "modexp_eip2565")
+}
+
+func TestModExpEIP7883(t *testing.T) {
+	testPrecompileVectors(t, NewModExp(ModExpPricingEIP7883), "modexp_eip7883")
+}
+
+func TestModExpPricing(t *testing.T) {
+	tests := []struct {
+		fork     Fork
+		expected ModExpPricing
+	}{
+		{Byzantium, ModExpPricingEIP198},
+		{Istanbul, ModExpPricingEIP198},
+		{Berlin, ModExpPricingEIP2565},
+		{Prague, ModExpPricingEIP2565},
+		{Osaka, ModExpPricingEIP7883},
+	}
+	for _, test := range tests {
+		if pricing := modExpPricing(test.fork); pricing != test.expected {
+			t.Errorf("modExpPricing(%s) returned %d, wanted %d", test.fork, pricing, test.expected)
+		}
+	}
+}
+
+func TestModExpZeroLengths(t *testing.T) {
+	header := func(baseLen, expLen, modLen byte) string {
+		return common.Bytes2Hex(common.LeftPadBytes([]byte{baseLen}, 32)) +
+			common.Bytes2Hex(common.LeftPadBytes([]byte{expLen}, 32)) +
+			common.Bytes2Hex(common.LeftPadBytes([]byte{modLen}, 32))
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected []byte
+	}{
+		{"empty input", "", []byte{}},
+		{"empty base and modulus", header(0, 1, 0) + "02", []byte{}},
+		{"empty modulus", header(1, 1, 0) + "0203", []byte{}},
+		{"empty base", header(0, 1, 1) + "0205", []byte{0x00}},
+		{"empty exponent", header(1, 0, 1) + "0205", []byte{0x01}},
+		{"empty exponent with modulus one", header(1, 0, 1) + "0201", []byte{0x00}},
+		{"zero modulus", header(1, 1, 2) + "02030000", []byte{0x00, 0x00}},
+		{"truncated input", header(1, 1, 2) + "0203", []byte{0x00, 0x00}},
+		{"short modulus is right-padded", header(1, 1, 2) + "020301", []byte{0x00, 0x08}},
+	}
+
+	p := NewModExp(ModExpPricingEIP2565)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output, err := p.Run(common.FromHex(test.input))
+			if err != nil {
+				t.Fatalf("Run() returned an unexpected error: %v", err)
+			}
+			if !bytes.Equal(output, test.expected) {
+				t.Errorf("Expected output %x, got %x", test.expected, output)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package evm
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrInternal is returned when the EVM reaches an inconsistent state, e.g. a panic or a broken invariant.
+	// It denotes a bug in the interpreter or in a custom operation, not in the executed code.
+	ErrInternal = errors.New("internal error")
+)
+
+// Check the invariants of the EVM after the execution of an operation.
+// The stack size before the operation is used to check the number of items it popped and pushed.
+func (e *EVM) checkInvariants(op *Operation, stackSize int) error {
+	if expected := stackSize - op.StackIn + op.StackOut; e.stack.Size() != expected {
+		return fmt.Errorf("%w: stack size %d after %s, expected %d", ErrInternal, e.stack.Size(), op.Name, expected)
+	}
+	if e.stack.Size() > MAX_STACK_SIZE {
+		return fmt.Errorf("%w: stack size %d exceeds the maximum stack size", ErrInternal, e.stack.Size())
+	}
+
+	size := uint64(e.memory.Size())
+	if size%32 != 0 {
+		return fmt.Errorf("%w: memory size %d is not a multiple of 32 bytes", ErrInternal, size)
+	}
+	if size > maxMemorySize || (e.env.maxMemory > 0 && size > e.env.maxMemory) {
+		return fmt.Errorf("%w: memory size %d exceeds the maximum memory size", ErrInternal, size)
+	}
+
+	if e.state.pc < 0 {
+		return fmt.Errorf("%w: negative program counter %d", ErrInternal, e.state.pc)
+	}
+	return nil
+}
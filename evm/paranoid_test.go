@@ -0,0 +1,94 @@
+package evm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/holiman/uint256"
+)
+
+func TestRunRecoversFromPanics(t *testing.T) {
+	crash := &Operation{
+		Name:    "CRASH",
+		Execute: func(ctx *OperationContext) error { panic("boom") },
+	}
+
+	// PUSH1 1, CRASH
+	evm := NewEVM([]byte{0x60, 0x01, 0xef})
+	if err := evm.RegisterOperation(0xef, crash); err != nil {
+		t.Fatalf("RegisterOperation() returned an unexpected error: %v", err)
+	}
+	result := evm.Run()
+	if !errors.Is(result.Err, ErrInternal) {
+		t.Fatalf("Run() returned an unexpected error: %v, wanted: %v", result.Err, ErrInternal)
+	}
+
+	var vmErr *VMError
+	if !errors.As(result.Err, &vmErr) || vmErr.PC != 2 || vmErr.OpName != "CRASH" {
+		t.Errorf("Expected a VMError for CRASH at pc 2, got %v", result.Err)
+	}
+}
+
+// panickingTracer panics in the hook named by panicOn.
+type panickingTracer struct {
+	recordingTracer
+	panicOn string
+}
+
+func (t *panickingTracer) OnFault(pc int, opcode OpCode, gas, cost uint64, scope IScope, depth int, err error) {
+	if t.panicOn == "OnFault" {
+		panic("fault")
+	}
+}
+
+func (t *panickingTracer) OnExit(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+	if t.panicOn == "OnExit" {
+		panic("exit")
+	}
+}
+
+func TestRunRecoversFromTracerPanics(t *testing.T) {
+	// PUSH1 1, JUMP to an invalid destination, so OnFault is called before OnExit.
+	code := []byte{0x60, 0x01, 0x56}
+	for _, hook := range []string{"OnFault", "OnExit"} {
+		result := NewEVM(code, WithTracer(&panickingTracer{panicOn: hook})).Run()
+		if !errors.Is(result.Err, ErrInternal) {
+			t.Errorf("Run() returned an unexpected error when %s panics: %v, wanted: %v", hook, result.Err, ErrInternal)
+		}
+	}
+
+	// A successful execution also fails if OnExit panics.
+	result := NewEVM([]byte{0x00}, WithTracer(&panickingTracer{panicOn: "OnExit"})).Run()
+	if !errors.Is(result.Err, ErrInternal) || result.GasUsed != DefaultGasLimit {
+		t.Errorf("Run() returned an unexpected result: %v, %d gas used", result.Err, result.GasUsed)
+	}
+}
+
+func TestParanoidMode(t *testing.T) {
+	// Define an operation which pushes an item without declaring it.
+	liar := &Operation{
+		Name:    "LIAR",
+		Execute: func(ctx *OperationContext) error { return ctx.Stack.Push(uint256.NewInt(1)) },
+	}
+
+	// Without paranoid mode, the broken invariant goes unnoticed.
+	evm := NewEVM([]byte{0xef})
+	evm.RegisterOperation(0xef, liar)
+	if result := evm.Run(); result.Err != nil {
+		t.Errorf("Run() returned an unexpected error: %v", result.Err)
+	}
+
+	evm = NewEVM([]byte{0xef}, WithParanoidMode())
+	evm.RegisterOperation(0xef, liar)
+	if result := evm.Run(); !errors.Is(result.Err, ErrInternal) {
+		t.Errorf("Run() returned an unexpected error: %v, wanted: %v", result.Err, ErrInternal)
+	}
+}
+
+func TestParanoidModeWithBuiltinOperations(t *testing.T) {
+	// PUSH1 0x2a, PUSH1 0, MSTORE, PUSH1 32, PUSH1 0, KECCAK256, DUP1, SWAP1, POP, PUSH1 0, PUSH1 0, RETURN
+	code := []byte{0x60, 0x2a, 0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0x20, 0x80, 0x90, 0x50, 0x60, 0x00, 0x60, 0x00, 0xf3}
+	if result := NewEVM(code, WithParanoidMode()).Run(); result.Err != nil {
+		t.Errorf("Run() returned an unexpected error: %v", result.Err)
+	}
+}
@@ -0,0 +1,181 @@
+package evm
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+)
+
+var (
+	// ErrOutOfGas is returned when there is not enough gas left to perform an operation.
+	ErrOutOfGas = errors.New("out of gas")
+	// ErrPrecompileNotFound is returned when calling an address that doesn't hold a precompiled contract.
+	ErrPrecompileNotFound = errors.New("precompile not found")
+	// ErrInvalidPrecompile is returned when registering a nil precompiled contract.
+	ErrInvalidPrecompile = errors.New("invalid precompile")
+)
+
+// IPrecompile defines the methods that a precompiled contract implementation should have.
+type IPrecompile interface {
+	// RequiredGas returns the amount of gas needed to run the precompile with the given input.
+	RequiredGas(input []byte) uint64
+
+	// Run executes the precompile with the given input and returns its output.
+	// It returns an error if the input is invalid.
+	Run(input []byte) ([]byte, error)
+}
+
+// IStatefulPrecompile defines a precompiled contract that needs to know about the context of its caller.
+// When a precompile implements it, RunWithContext is called instead of Run.
+type IStatefulPrecompile interface {
+	IPrecompile
+
+	// RunWithContext executes the precompile with the given context and input, and returns its output.
+	// It returns an error if the input is invalid.
+	RunWithContext(ctx PrecompileContext, input []byte) ([]byte, error)
+}
+
+// PrecompileContext represents the context in which a precompiled contract is called.
+type PrecompileContext struct {
+	// Network upgrade whose rules apply to the execution.
+	Fork Fork
+
+	// Address of the precompiled contract.
+	Address common.Address
+
+	// Address of the account calling the precompiled contract.
+	Caller common.Address
+
+	// Value, in wei, passed to the precompiled contract.
+	Value *uint256.Int
+
+	// Read-only view of the storage of the EVM instance, which is shared by all the accounts whatever the caller.
+	Storage IStorageReader
+}
+
+// IPrecompileRegistry defines the methods to manage the precompiled contracts of an EVM instance.
+type IPrecompileRegistry interface {
+	// RegisterPrecompile registers a precompiled contract at the given address.
+	// It overrides the built-in precompiled contract at this address, if any.
+	// The registration only applies to this EVM instance.
+	// It returns ErrInvalidPrecompile if the precompiled contract is nil.
+	RegisterPrecompile(address common.Address, p IPrecompile) error
+
+	// Precompile returns the precompiled contract at the given address.
+	// The boolean is false if there is no precompiled contract at this address.
+	Precompile(address common.Address) (IPrecompile, bool)
+
+	// CallPrecompile runs the precompiled contract at the given address with the given input and gas.
+	// The caller and the value, which may be nil for zero, are passed to stateful precompiles.
+	// It returns the output of the precompile and the remaining gas.
+	// It returns ErrPrecompileNotFound if there is no precompiled contract at this address.
+	CallPrecompile(caller, address common.Address, input []byte, gas uint64, value *uint256.Int) ([]byte, uint64, error)
+}
+
+func (e *EVM) RegisterPrecompile(address common.Address, p IPrecompile) error {
+	if p == nil {
+		return ErrInvalidPrecompile
+	}
+	e.precompiles[address] = p
+	return nil
+}
+
+func (e *EVM) Precompile(address common.Address) (IPrecompile, bool) {
+	p, ok := e.precompiles[address]
+	return p, ok
+}
+
+func (e *EVM) CallPrecompile(caller, address common.Address, input []byte, gas uint64, value *uint256.Int) ([]byte, uint64, error) {
+	p, ok := e.precompiles[address]
+	if !ok {
+		return nil, gas, ErrPrecompileNotFound
+	}
+
+	// Stateful precompiles get access to the context of the caller.
+	if sp, ok := p.(IStatefulPrecompile); ok {
+		if value == nil {
+			value = new(uint256.Int)
+		}
+		storage := e.storage
+		if e.tracer != nil {
+			storage = &tracingStorage{IStorage: storage, evm: e}
+		}
+		ctx := PrecompileContext{
+			Fork:    e.env.fork,
+			Address: address,
+			Caller:  caller,
+			Value:   new(uint256.Int).Set(value),
+			Storage: &readOnlyStorage{storage: storage},
+		}
+		p = &contextualPrecompile{IStatefulPrecompile: sp, ctx: ctx}
+	}
+	return RunPrecompile(p, input, gas)
+}
+
+// contextualPrecompile binds a stateful precompiled contract to the context of a call.
+type contextualPrecompile struct {
+	IStatefulPrecompile
+	ctx PrecompileContext
+}
+
+func (c *contextualPrecompile) Run(input []byte) ([]byte, error) {
+	return c.RunWithContext(c.ctx, input)
+}
+
+// PrecompiledContracts returns the set of precompiled contracts active at the given fork, indexed by address.
+func PrecompiledContracts(fork Fork) map[common.Address]IPrecompile {
+	contracts := make(map[common.Address]IPrecompile)
+	if fork >= Byzantium {
+		contracts[common.BytesToAddress([]byte{0x05})] = &modExp{pricing: modExpPricing(fork)}
+	}
+	if fork >= Istanbul {
+		contracts[common.BytesToAddress([]byte{0x06})] = &bn254Add{gas: bn254AddGasIstanbul}
+		contracts[common.BytesToAddress([]byte{0x07})] = &bn254ScalarMul{gas: bn254ScalarMulGasIstanbul}
+		contracts[common.BytesToAddress([]byte{0x08})] = &bn254Pairing{baseGas: bn254PairingBaseGasIstanbul, perPairGas: bn254PairingPerPairGasIstanbul}
+		contracts[common.BytesToAddress([]byte{0x09})] = &blake2F{}
+	} else if fork >= Byzantium {
+		contracts[common.BytesToAddress([]byte{0x06})] = &bn254Add{gas: bn254AddGasByzantium}
+		contracts[common.BytesToAddress([]byte{0x07})] = &bn254ScalarMul{gas: bn254ScalarMulGasByzantium}
+		contracts[common.BytesToAddress([]byte{0x08})] = &bn254Pairing{baseGas: bn254PairingBaseGasByzantium, perPairGas: bn254PairingPerPairGasByzantium}
+	}
+	if fork >= Cancun {
+		contracts[common.BytesToAddress([]byte{0x0a})] = &kzgPointEvaluation{}
+	}
+	if fork >= Prague {
+		contracts[common.BytesToAddress([]byte{0x0b})] = &bls12381G1Add{}
+		contracts[common.BytesToAddress([]byte{0x0c})] = &bls12381G1MSM{}
+		contracts[common.BytesToAddress([]byte{0x0d})] = &bls12381G2Add{}
+		contracts[common.BytesToAddress([]byte{0x0e})] = &bls12381G2MSM{}
+		contracts[common.BytesToAddress([]byte{0x0f})] = &bls12381Pairing{}
+		contracts[common.BytesToAddress([]byte{0x10})] = &bls12381MapG1{}
+		contracts[common.BytesToAddress([]byte{0x11})] = &bls12381MapG2{}
+	}
+	return contracts
+}
+
+// RunPrecompile charges the gas required by the precompile and runs it.
+// It returns the output of the precompile and the remaining gas.
+// If the supplied gas is not enough, it returns ErrOutOfGas and the precompile is not executed.
+func RunPrecompile(p IPrecompile, input []byte, suppliedGas uint64) ([]byte, uint64, error) {
+	requiredGas := p.RequiredGas(input)
+	if suppliedGas < requiredGas {
+		return nil, 0, ErrOutOfGas
+	}
+	output, err := p.Run(input)
+	return output, suppliedGas - requiredGas, err
+}
+
+// Return a copy of the data in the [start, start+size) range.
+// The data is right-padded with zeros if the range exceeds its length.
+func getData(data []byte, start, size uint64) []byte {
+	length := uint64(len(data))
+	if start > length {
+		start = length
+	}
+	end := start + size
+	if end > length || end < start {
+		end = length
+	}
+	return common.RightPadBytes(data[start:end], int(size))
+}
@@ -0,0 +1,245 @@
+package evm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+)
+
+// precompileTest defines an input/output test vector for a precompiled contract.
+// The vectors are taken from https://github.com/ethereum/go-ethereum/tree/master/core/vm/testdata/precompiles.
+type precompileTest struct {
+	Input    string
+	Expected string
+	Gas      uint64
+	Name     string
+}
+
+// precompileFailureTest defines an input/error test vector for a precompiled contract.
+type precompileFailureTest struct {
+	Input         string
+	ExpectedError string
+	Name          string
+}
+
+func TestPrecompiledContracts(t *testing.T) {
+	modExpAddress := common.BytesToAddress([]byte{0x05})
+	if _, ok := PrecompiledContracts(Homestead)[modExpAddress]; ok {
+		t.Errorf("MODEXP should not be active before Byzantium")
+	}
+	if _, ok := PrecompiledContracts(Byzantium)[modExpAddress]; !ok {
+		t.Errorf("MODEXP should be active from Byzantium")
+	}
+}
+
+func TestRunPrecompileOutOfGas(t *testing.T) {
+	p := NewModExp(ModExpPricingEIP2565)
+	input := common.FromHex("0x000000000000000000000000000000000000000000000000000000000000000100000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000001020305")
+
+	requiredGas := p.RequiredGas(input)
+	if _, _, err := RunPrecompile(p, input, requiredGas-1); err != ErrOutOfGas {
+		t.Errorf("RunPrecompile() returned an unexpected error: %v, wanted: %v", err, ErrOutOfGas)
+	}
+
+	output, remainingGas, err := RunPrecompile(p, input, requiredGas+10)
+	if err != nil {
+		t.Errorf("RunPrecompile() returned an unexpected error: %v", err)
+	}
+	if remainingGas != 10 {
+		t.Errorf("Expected remaining gas %d, got %d", 10, remainingGas)
+	}
+	if !bytes.Equal(output, []byte{0x03}) {
+		t.Errorf("Expected output %x, got %x", []byte{0x03}, output)
+	}
+}
+
+// Helper function to run the test vectors of a precompiled contract stored in testdata/precompiles.
+func testPrecompileVectors(t *testing.T, p IPrecompile, name string) {
+	data, err := os.ReadFile(fmt.Sprintf("testdata/precompiles/%s.json", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests []precompileTest
+	if err = json.Unmarshal(data, &tests); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			input := common.FromHex(test.Input)
+			if gas := p.RequiredGas(input); gas != test.Gas {
+				t.Errorf("RequiredGas() returned %d, wanted %d", gas, test.Gas)
+			}
+
+			output, _, err := RunPrecompile(p, input, test.Gas)
+			if err != nil {
+				t.Fatalf("RunPrecompile() returned an unexpected error: %v", err)
+			}
+			if expected := common.FromHex(test.Expected); !bytes.Equal(output, expected) {
+				t.Errorf("Expected output %x, got %x", expected, output)
+			}
+		})
+	}
+}
+
+// Helper function to run the failure test vectors of a precompiled contract stored in testdata/precompiles.
+func testPrecompileFailureVectors(t *testing.T, p IPrecompile, name string) {
+	data, err := os.ReadFile(fmt.Sprintf("testdata/precompiles/fail-%s.json", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests []precompileFailureTest
+	if err = json.Unmarshal(data, &tests); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			input := common.FromHex(test.Input)
+			_, _, err := RunPrecompile(p, input, p.RequiredGas(input))
+			if err == nil || err.Error() != test.ExpectedError {
+				t.Errorf("RunPrecompile() returned an unexpected error: %v, wanted: %v", err, test.ExpectedError)
+			}
+		})
+	}
+}
+
+// echoPrecompile is a custom precompiled contract returning its input.
+type echoPrecompile struct{}
+
+func (p *echoPrecompile) RequiredGas(input []byte) uint64 {
+	return uint64(len(input))
+}
+
+func (p *echoPrecompile) Run(input []byte) ([]byte, error) {
+	return input, nil
+}
+
+// callerPrecompile is a custom stateful precompiled contract returning its caller, the call value and the storage value at key 0.
+type callerPrecompile struct {
+	echoPrecompile
+}
+
+func (p *callerPrecompile) RunWithContext(ctx PrecompileContext, input []byte) ([]byte, error) {
+	value, stored := ctx.Value.Bytes32(), ctx.Storage.Load(0)
+	return append(append(ctx.Caller.Bytes(), value[:]...), stored[:]...), nil
+}
+
+func TestRegisterPrecompile(t *testing.T) {
+	address := common.BytesToAddress([]byte{0x01, 0x00})
+	evm := NewEVM(nil)
+	if _, ok := evm.Precompile(address); ok {
+		t.Fatalf("Precompile() should not find a contract before registration")
+	}
+	if _, _, err := evm.CallPrecompile(common.Address{}, address, nil, 100, nil); err != ErrPrecompileNotFound {
+		t.Errorf("CallPrecompile() returned an unexpected error: %v, wanted: %v", err, ErrPrecompileNotFound)
+	}
+
+	if err := evm.RegisterPrecompile(address, &echoPrecompile{}); err != nil {
+		t.Fatalf("RegisterPrecompile() returned an unexpected error: %v", err)
+	}
+	output, remainingGas, err := evm.CallPrecompile(common.Address{}, address, []byte{1, 2, 3}, 100, nil)
+	if err != nil {
+		t.Fatalf("CallPrecompile() returned an unexpected error: %v", err)
+	}
+	if !bytes.Equal(output, []byte{1, 2, 3}) {
+		t.Errorf("Expected output %x, got %x", []byte{1, 2, 3}, output)
+	}
+	if remainingGas != 97 {
+		t.Errorf("Expected remaining gas %d, got %d", 97, remainingGas)
+	}
+
+	// The registration should only apply to this instance.
+	if _, ok := NewEVM(nil).Precompile(address); ok {
+		t.Errorf("Precompile() should not find a contract registered on another instance")
+	}
+
+	if err := evm.RegisterPrecompile(address, nil); err != ErrInvalidPrecompile {
+		t.Errorf("RegisterPrecompile() returned an unexpected error: %v, wanted: %v", err, ErrInvalidPrecompile)
+	}
+	if p, ok := evm.Precompile(address); !ok || p == nil {
+		t.Errorf("A failed registration should keep the registered contract")
+	}
+}
+
+func TestRegisterPrecompileOverridesBuiltin(t *testing.T) {
+	address := common.BytesToAddress([]byte{0x05})
+	evm := NewEVM(nil)
+	if p, ok := evm.Precompile(address); !ok || p == nil {
+		t.Fatalf("Precompile() should find the built-in MODEXP contract")
+	}
+
+	if err := evm.RegisterPrecompile(address, &echoPrecompile{}); err != nil {
+		t.Fatalf("RegisterPrecompile() returned an unexpected error: %v", err)
+	}
+	output, _, err := evm.CallPrecompile(common.Address{}, address, []byte{0xff}, 100, nil)
+	if err != nil {
+		t.Fatalf("CallPrecompile() returned an unexpected error: %v", err)
+	}
+	if !bytes.Equal(output, []byte{0xff}) {
+		t.Errorf("Expected output %x, got %x", []byte{0xff}, output)
+	}
+}
+
+func TestRegisterStatefulPrecompile(t *testing.T) {
+	self := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	evm := NewEVM(nil, WithAddress(self))
+	evm.(*EVM).storage.Store(0, [32]byte{31: 0x2a})
+
+	address := common.BytesToAddress([]byte{0x01, 0x01})
+	if err := evm.RegisterPrecompile(address, &callerPrecompile{}); err != nil {
+		t.Fatalf("RegisterPrecompile() returned an unexpected error: %v", err)
+	}
+	output, _, err := evm.CallPrecompile(self, address, nil, 100, uint256.NewInt(7))
+	if err != nil {
+		t.Fatalf("CallPrecompile() returned an unexpected error: %v", err)
+	}
+
+	expected := append(append(self.Bytes(), common.LeftPadBytes([]byte{7}, 32)...), common.LeftPadBytes([]byte{0x2a}, 32)...)
+	if !bytes.Equal(output, expected) {
+		t.Errorf("Expected output %x, got %x", expected, output)
+	}
+
+	// A nil value is passed as zero.
+	caller := common.HexToAddress("0x2222222222222222222222222222222222222222")
+	output, _, err = evm.CallPrecompile(caller, address, nil, 100, nil)
+	if err != nil {
+		t.Fatalf("CallPrecompile() returned an unexpected error: %v", err)
+	}
+	expected = append(append(caller.Bytes(), make([]byte, 32)...), common.LeftPadBytes([]byte{0x2a}, 32)...)
+	if !bytes.Equal(output, expected) {
+		t.Errorf("Expected output %x, got %x", expected, output)
+	}
+}
+
+func TestStatefulPrecompileStorageReadsAreTraced(t *testing.T) {
+	tracer := &recordingTracer{}
+	evm := NewEVM(nil, WithTracer(tracer))
+	evm.(*EVM).storage.Store(0, [32]byte{31: 0x2a})
+
+	address := common.BytesToAddress([]byte{0x01, 0x01})
+	if err := evm.RegisterPrecompile(address, &callerPrecompile{}); err != nil {
+		t.Fatalf("RegisterPrecompile() returned an unexpected error: %v", err)
+	}
+	if _, _, err := evm.CallPrecompile(common.Address{}, address, nil, 100, nil); err != nil {
+		t.Fatalf("CallPrecompile() returned an unexpected error: %v", err)
+	}
+	if len(tracer.events) != 1 || tracer.events[0] != "storage read 0 2a" {
+		t.Errorf("Unexpected events: %q", tracer.events)
+	}
+}
+
+func TestBuiltinPrecompilesFollowFork(t *testing.T) {
+	address := common.BytesToAddress([]byte{0x0b})
+	if _, ok := NewEVM(nil, WithFork(Cancun)).Precompile(address); ok {
+		t.Errorf("BLS12-381 precompiles should not be active in Cancun")
+	}
+	if _, ok := NewEVM(nil, WithFork(Prague)).Precompile(address); !ok {
+		t.Errorf("BLS12-381 precompiles should be active in Prague")
+	}
+}
@@ -15,11 +15,21 @@ type ISHA3Ops interface {
 }
 
 func (e *EVM) Keccak256() error {
-	op := func(operands ...*uint256.Int) *uint256.Int {
-		offset, size := operands[0], operands[1]
-		data := e.memory.Load(int(offset.Uint64()), int(size.Uint64()))
-		hash := crypto.Keccak256(data)
-		return new(uint256.Int).SetBytes(hash)
+	// Load offset and size from the stack.
+	offset, err := e.stack.Pop()
+	if err != nil {
+		return err
 	}
-	return e.performBinaryStackOperation(2, op)
+	size, err := e.stack.Pop()
+	if err != nil {
+		return err
+	}
+
+	// Hash the data in memory.
+	start, length, err := e.memoryRegion(offset, size)
+	if err != nil {
+		return err
+	}
+	hash := crypto.Keccak256(e.memory.Load(start, length))
+	return e.stack.Push(new(uint256.Int).SetBytes(hash))
 }
@@ -1,6 +1,7 @@
 package evm
 
 import (
+	"math"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/crypto"
@@ -21,3 +22,28 @@ func TestKeccak256(t *testing.T) {
 	expectedStack := []uint64{1, value}
 	testStackOperationWithNewEVM(t, op, nil, initialStack, expectedStack, memory, nil, nil)
 }
+
+func TestKeccak256WithLargeOffsets(t *testing.T) {
+	large := new(uint256.Int).Lsh(uint256.NewInt(1), 255)
+	tests := []struct {
+		name         string
+		offset, size *uint256.Int
+		expectedErr  error
+	}{
+		{"offset 2^255", large, uint256.NewInt(1), ErrOutOfGas},
+		{"size 2^255", uint256.NewInt(0), large, ErrOutOfGas},
+		{"overflowing region", uint256.NewInt(math.MaxUint64), uint256.NewInt(32), ErrOutOfGas},
+		{"empty region at offset 2^255", large, uint256.NewInt(0), nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			evm := NewEVM(nil)
+			testEvm := evm.(ExtendedEVM)
+			testEvm.HelperPush(test.size)
+			testEvm.HelperPush(test.offset)
+			if err := evm.Keccak256(); err != test.expectedErr {
+				t.Errorf("Operation returned an unexpected error: %v, wanted: %v", err, test.expectedErr)
+			}
+		})
+	}
+}
@@ -9,9 +9,6 @@ import (
 var (
 	// ErrPushSize is returned when the push size is outside the valid range of 1 to 32.
 	ErrInvalidPushSize = errors.New("invalid push size")
-	// ErrPushSizeExceedsCodeSize is returned when trying the push size exceeds the code size.
-	ErrPushSizeExceedsCodeSize = errors.New("push size exceeds code size")
-
 	// ErrDupSize is returned when the dup size is outside the valid range of 1 to 16.
 	ErrInvalidDupSize = errors.New("invalid dup size")
 
@@ -277,12 +274,12 @@ func (e *EVM) pushN(n int) error {
 		// Note that the EVM exposes Push0() but the logic is different and does not rely on pushN().
 		return ErrInvalidPushSize
 	}
-	if len(e.env.code) <= e.state.pc+n {
-		return ErrPushSizeExceedsCodeSize
+	// Like go-ethereum, data truncated by the end of the code is padded with zeros on the right.
+	data := make([]byte, n)
+	if start := e.state.pc + 1; start < len(e.env.code) {
+		copy(data, e.env.code[start:])
 	}
-
-	code := e.env.code[e.state.pc+1 : e.state.pc+1+n]
-	value := new(uint256.Int).SetBytes(code)
+	value := new(uint256.Int).SetBytes(data)
 	if err := e.stack.Push(value); err != nil {
 		return err
 	}
@@ -31,13 +31,20 @@ func TestPushN(t *testing.T) {
 	}
 }
 
-func TestPushSizeExceedsCodeSize(t *testing.T) {
-	// Create a new EVM.
-	evm := NewEVM(nil)
+func TestPushTruncated(t *testing.T) {
+	// PUSH3 0x0102, truncated by the end of the code.
+	result := NewEVM([]byte{0x62, 0x01, 0x02}).Run()
+	if result.Err != nil {
+		t.Fatalf("Run() returned an unexpected error: %v", result.Err)
+	}
+	if len(result.Stack) != 1 || result.Stack[0].Uint64() != 0x010200 {
+		t.Errorf("The data should be padded with zeros on the right, got: %v", result.Stack)
+	}
 
-	// Try to push the first element from code (which is empty).
-	if err := evm.Push1(); err == nil {
-		t.Errorf("Operation returned an unexpected error: %v, wanted: %v", err, ErrPushSizeExceedsCodeSize)
+	// PUSH1 without any data.
+	result = NewEVM([]byte{0x60}).Run()
+	if result.Err != nil || len(result.Stack) != 1 || !result.Stack[0].IsZero() {
+		t.Errorf("Run() returned an unexpected result: %v, %v", result.Stack, result.Err)
 	}
 }
 
@@ -0,0 +1,120 @@
+package evm
+
+import (
+	"github.com/holiman/uint256"
+)
+
+// IStepper defines the method to execute the code one instruction at a time.
+type IStepper interface {
+	// Step executes the instruction at the current program counter and returns what happened.
+	// Once the execution halted, it doesn't execute anything and reports the same outcome.
+	Step() *StepResult
+}
+
+// StepResult represents the outcome of the execution of a single instruction.
+type StepResult struct {
+	// Program counter of the instruction.
+	PC int
+
+	// Opcode of the instruction.
+	OpCode OpCode
+
+	// Name of the instruction.
+	OpName string
+
+	// Gas consumed by the instruction, including the memory expansion.
+	GasCost uint64
+
+	// Stack items consumed by the instruction, from the top of the stack.
+	StackPopped []*uint256.Int
+
+	// Stack items produced by the instruction, from the top of the stack.
+	StackPushed []*uint256.Int
+
+	// Memory regions written by the instruction, in order.
+	MemoryWrites []MemoryWrite
+
+	// True if the instruction halted the execution.
+	Halted bool
+
+	// Error which halted the execution, if any.
+	Err error
+
+	// Result of the execution, once it halted.
+	Result *ExecutionResult
+}
+
+// MemoryWrite represents data written to memory at a given offset.
+type MemoryWrite struct {
+	Offset int
+	Data   []byte
+}
+
+func (e *EVM) Step() *StepResult {
+	step := &StepResult{PC: e.state.pc, OpCode: STOP, OpName: "STOP"}
+	if !e.state.halted && e.state.pc < len(e.env.code) {
+		step.OpCode = OpCode(e.env.code[e.state.pc])
+		step.OpName = e.instructions.Name(step.OpCode)
+	}
+
+	// Record the stack items consumed by the instruction.
+	var stackIn, stackOut int
+	if op := e.instructions[step.OpCode]; op != nil && !e.state.halted {
+		stackIn, stackOut = op.StackIn, op.StackOut
+	}
+	step.StackPopped = e.topStackItems(stackIn)
+
+	// Record the memory writes while the instruction executes.
+	memory := &recordingMemory{IMemory: e.memory}
+	e.memory = memory
+	gas := e.state.gas
+	halted, err := e.step()
+	e.memory = memory.IMemory
+
+	step.GasCost = gas - e.state.gas
+	step.MemoryWrites = memory.writes
+	step.Halted, step.Err = halted, err
+	if err == nil {
+		step.StackPushed = e.topStackItems(stackOut)
+	}
+	if halted {
+		step.Result = e.result()
+	}
+	return step
+}
+
+// Return a copy of the top n stack items, from the top of the stack.
+// It returns fewer items if the stack is smaller.
+func (e *EVM) topStackItems(n int) []*uint256.Int {
+	items := make([]*uint256.Int, 0, n)
+	for i := 1; i <= n && i <= e.stack.Size(); i++ {
+		item, _ := e.stack.Get(i)
+		items = append(items, new(uint256.Int).Set(item))
+	}
+	return items
+}
+
+// recordingMemory records the data written to the underlying memory.
+type recordingMemory struct {
+	IMemory
+	writes []MemoryWrite
+}
+
+func (m *recordingMemory) Store(value []byte, offset int) {
+	m.record(value, offset)
+	m.IMemory.Store(value, offset)
+}
+
+func (m *recordingMemory) StoreByte(value byte, offset int) {
+	m.record([]byte{value}, offset)
+	m.IMemory.StoreByte(value, offset)
+}
+
+func (m *recordingMemory) StoreWord(word [32]byte, offset int) {
+	m.record(word[:], offset)
+	m.IMemory.StoreWord(word, offset)
+}
+
+func (m *recordingMemory) record(value []byte, offset int) {
+	m.writes = append(m.writes, MemoryWrite{Offset: offset, Data: append([]byte{}, value...)})
+}
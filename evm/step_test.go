@@ -0,0 +1,71 @@
+package evm
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestStep(t *testing.T) {
+	// PUSH1 0x2a, PUSH1 0, MSTORE8, STOP
+	evm := NewEVM([]byte{0x60, 0x2a, 0x60, 0x00, 0x53, 0x00}, WithGas(100))
+
+	step := evm.Step()
+	if step.PC != 0 || step.OpName != "PUSH1" || step.GasCost != 3 || step.Halted {
+		t.Errorf("Unexpected first step: %+v", step)
+	}
+	if len(step.StackPopped) != 0 || len(step.StackPushed) != 1 || step.StackPushed[0].Uint64() != 0x2a {
+		t.Errorf("Expected PUSH1 to push 0x2a, got %v", step.StackPushed)
+	}
+
+	evm.Step()
+	step = evm.Step()
+	if step.PC != 4 || step.OpCode != MSTORE8 {
+		t.Fatalf("Expected MSTORE8 at pc 4, got %s at pc %d", step.OpName, step.PC)
+	}
+	// 3 gas for MSTORE8 and 3 gas to expand the memory to 1 word.
+	if step.GasCost != 6 {
+		t.Errorf("Expected MSTORE8 to cost 6 gas, got %d", step.GasCost)
+	}
+	if len(step.StackPopped) != 2 || step.StackPopped[0].Uint64() != 0 || step.StackPopped[1].Uint64() != 0x2a {
+		t.Errorf("Expected MSTORE8 to pop [0 0x2a], got %v", step.StackPopped)
+	}
+	if len(step.MemoryWrites) != 1 || step.MemoryWrites[0].Offset != 0 || !bytes.Equal(step.MemoryWrites[0].Data, []byte{0x2a}) {
+		t.Errorf("Expected MSTORE8 to write 0x2a at offset 0, got %+v", step.MemoryWrites)
+	}
+
+	step = evm.Step()
+	if !step.Halted || step.OpCode != STOP || step.Err != nil {
+		t.Errorf("Expected STOP to halt the execution, got %+v", step)
+	}
+	if step.Result == nil || step.Result.GasUsed != 12 {
+		t.Errorf("Expected the result of the execution once halted, got %+v", step.Result)
+	}
+}
+
+func TestStepAfterHalt(t *testing.T) {
+	// PUSH1 1, INVALID
+	evm := NewEVM([]byte{0x60, 0x01, 0xef}, WithGas(100))
+	evm.Step()
+	step := evm.Step()
+	if !step.Halted || !errors.Is(step.Err, ErrInvalidOpcode) {
+		t.Fatalf("Expected the invalid opcode to halt the execution, got %+v", step)
+	}
+
+	// Stepping again doesn't execute anything and reports the same error.
+	step = evm.Step()
+	if !step.Halted || !errors.Is(step.Err, ErrInvalidOpcode) || step.GasCost != 0 {
+		t.Errorf("Expected the execution to remain halted, got %+v", step)
+	}
+	if result := evm.Run(); !errors.Is(result.Err, ErrInvalidOpcode) {
+		t.Errorf("Run() returned an unexpected error: %v, wanted: %v", result.Err, ErrInvalidOpcode)
+	}
+}
+
+func TestStepPastEndOfCode(t *testing.T) {
+	evm := NewEVM(nil)
+	step := evm.Step()
+	if !step.Halted || step.Err != nil || step.OpCode != STOP {
+		t.Errorf("Expected an implicit STOP, got %+v", step)
+	}
+}
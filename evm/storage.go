@@ -9,6 +9,16 @@ type IStorage interface {
 	// Load retrieves a 32-byte word from storage using the specified key.
 	// If the key does not exist in the storage, it returns an empty 32-byte word.
 	Load(key int) [32]byte
+
+	// Dump returns a copy of all the key-value pairs in storage.
+	Dump() map[int][32]byte
+}
+
+// IStorageReader defines the read-only methods of a storage implementation.
+type IStorageReader interface {
+	// Load retrieves a 32-byte word from storage using the specified key.
+	// If the key does not exist in the storage, it returns an empty 32-byte word.
+	Load(key int) [32]byte
 }
 
 // Storage represents a word-addressable storage structure.
@@ -28,3 +38,21 @@ func (s *Storage) Store(key int, value [32]byte) {
 func (s *Storage) Load(key int) [32]byte {
 	return s.data[key]
 }
+
+func (s *Storage) Dump() map[int][32]byte {
+	dump := make(map[int][32]byte, len(s.data))
+	for key, value := range s.data {
+		dump[key] = value
+	}
+	return dump
+}
+
+// readOnlyStorage restricts a storage to its read-only methods.
+// It prevents callers from writing to the storage by asserting the underlying type.
+type readOnlyStorage struct {
+	storage IStorage
+}
+
+func (s *readOnlyStorage) Load(key int) [32]byte {
+	return s.storage.Load(key)
+}
@@ -42,3 +42,20 @@ func TestStoreAndLoad(t *testing.T) {
 		t.Errorf("Expected %v, got %v", emptyValue, loaded3)
 	}
 }
+
+func TestDump(t *testing.T) {
+	// Store a value in the storage.
+	s := NewStorage()
+	s.Store(1, [32]byte{0x1})
+
+	dump := s.Dump()
+	if len(dump) != 1 || dump[1] != [32]byte{0x1} {
+		t.Errorf("Dump() returned %v, wanted a single value at key 1", dump)
+	}
+
+	// Modifying the dump should not modify the storage.
+	dump[2] = [32]byte{0x2}
+	if value := s.Load(2); value != [32]byte{} {
+		t.Errorf("Dump() should return a copy of the storage")
+	}
+}
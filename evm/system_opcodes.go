@@ -0,0 +1,61 @@
+package evm
+
+import (
+	"errors"
+)
+
+var (
+	// ErrExecutionReverted is returned when the execution is halted by REVERT.
+	ErrExecutionReverted = errors.New("execution reverted")
+)
+
+// ISystemOps defines operations which halt the execution and return data to the caller.
+type ISystemOps interface {
+	// Return halts the execution and returns data from memory.
+	// It pops two items from the stack, offset and size.
+	// Then it copies the memory region [offset:offset+size] to the return data.
+	// Stack: [offset, size, ...] -> [...]
+	Return() error
+
+	// Revert halts the execution, reverts the state changes and returns data from memory.
+	// It pops two items from the stack, offset and size.
+	// Then it copies the memory region [offset:offset+size] to the return data.
+	// It always returns ErrExecutionReverted.
+	// Stack: [offset, size, ...] -> [...]
+	Revert() error
+}
+
+func (e *EVM) Return() error {
+	return e.loadReturnData()
+}
+
+func (e *EVM) Revert() error {
+	if err := e.loadReturnData(); err != nil {
+		return err
+	}
+	return ErrExecutionReverted
+}
+
+// Pop the offset and the size from the stack, and copy the memory region to the return data.
+func (e *EVM) loadReturnData() error {
+	// Load offset from the stack.
+	offset, err := e.stack.Pop()
+	if err != nil {
+		return err
+	}
+
+	// Load size from the stack.
+	size, err := e.stack.Pop()
+	if err != nil {
+		return err
+	}
+
+	// Copy the memory region, as the memory may still be modified.
+	start, length, err := e.memoryRegion(offset, size)
+	if err != nil {
+		return err
+	}
+	data := e.memory.Load(start, length)
+	e.state.returnData = append([]byte{}, data...)
+	return nil
+}
@@ -0,0 +1,34 @@
+package evm
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReturn(t *testing.T) {
+	op := func(evm IEVM) error { return evm.Return() }
+	initialMemory := []byte{0x01, 0x02, 0x03, 0x04}
+	evm := NewEVM(nil)
+	testStackOperationWithExistingEVM(t, evm, op, nil, []uint64{1, 2, 1}, []uint64{1}, initialMemory, initialMemory)
+
+	if data := evm.(*EVM).state.returnData; !bytes.Equal(data, []byte{0x02, 0x03}) {
+		t.Errorf("Expected return data %x, got %x", []byte{0x02, 0x03}, data)
+	}
+}
+
+func TestRevert(t *testing.T) {
+	op := func(evm IEVM) error { return evm.Revert() }
+	initialMemory := []byte{0x01, 0x02, 0x03, 0x04}
+	evm := NewEVM(nil)
+	testStackOperationWithExistingEVM(t, evm, op, ErrExecutionReverted, []uint64{4, 0}, nil, initialMemory, initialMemory)
+
+	if data := evm.(*EVM).state.returnData; !bytes.Equal(data, initialMemory) {
+		t.Errorf("Expected return data %x, got %x", initialMemory, data)
+	}
+}
+
+func TestReturnOnEmptyStack(t *testing.T) {
+	op := func(evm IEVM) error { return evm.Return() }
+	testStackOperationWithNewEVM(t, op, ErrStackUnderflow, nil, nil, nil, nil, nil)
+	testStackOperationWithNewEVM(t, op, ErrStackUnderflow, []uint64{1}, nil, nil, nil, nil)
+}
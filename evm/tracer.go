@@ -0,0 +1,196 @@
+package evm
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+)
+
+// ITracer defines the hooks invoked by the interpreter to observe the execution.
+// Tracers can embed NoopTracer to only implement the hooks they need.
+type ITracer interface {
+	// OnEnter is invoked when the call frame starts executing, before its first instruction.
+	OnEnter(depth int, from, to common.Address, input []byte, gas uint64, value *uint256.Int)
+
+	// OnOpcode is invoked before the execution of each instruction, once its gas is charged.
+	// The cost includes the static gas, the dynamic gas and the memory expansion.
+	// If the instruction fails before it can be executed (e.g. stack underflow or out of gas), it is invoked with the error instead.
+	OnOpcode(pc int, opcode OpCode, gas, cost uint64, scope IScope, returnData []byte, depth int, err error)
+
+	// OnOpcodeEnd is invoked once an instruction executed successfully, with the gas left after it.
+	OnOpcodeEnd(pc int, opcode OpCode, gas uint64, scope IScope, depth int)
+
+	// OnFault is invoked when an instruction, already reported by OnOpcode, fails during its execution.
+	OnFault(pc int, opcode OpCode, gas, cost uint64, scope IScope, depth int, err error)
+
+	// OnExit is invoked once the execution halted.
+	// The gas used doesn't include the refund.
+	OnExit(depth int, output []byte, gasUsed uint64, err error, reverted bool)
+
+	// OnStorageRead is invoked when an operation reads a storage slot.
+	OnStorageRead(address common.Address, key int, value [32]byte)
+
+	// OnStorageChange is invoked when an operation writes a new value to a storage slot.
+	OnStorageChange(address common.Address, key int, prev, value [32]byte)
+
+	// OnBalanceChange is invoked when an operation reports a change to the balance of an account.
+	// The EVM doesn't track balances itself, see OperationContext.ReportBalanceChange.
+	OnBalanceChange(address common.Address, prev, value *uint256.Int)
+
+	// OnLog is invoked when the execution emits an event.
+	// The log is shared with the result of the execution and must not be modified.
+	OnLog(log *Log)
+}
+
+// NoopTracer implements all the hooks of ITracer without doing anything.
+type NoopTracer struct{}
+
+func (NoopTracer) OnEnter(depth int, from, to common.Address, input []byte, gas uint64, value *uint256.Int) {
+}
+
+func (NoopTracer) OnOpcode(pc int, opcode OpCode, gas, cost uint64, scope IScope, returnData []byte, depth int, err error) {
+}
+
+func (NoopTracer) OnOpcodeEnd(pc int, opcode OpCode, gas uint64, scope IScope, depth int) {}
+
+func (NoopTracer) OnFault(pc int, opcode OpCode, gas, cost uint64, scope IScope, depth int, err error) {
+}
+
+func (NoopTracer) OnExit(depth int, output []byte, gasUsed uint64, err error, reverted bool) {}
+
+func (NoopTracer) OnStorageRead(address common.Address, key int, value [32]byte) {}
+
+func (NoopTracer) OnStorageChange(address common.Address, key int, prev, value [32]byte) {}
+
+func (NoopTracer) OnBalanceChange(address common.Address, prev, value *uint256.Int) {}
+
+func (NoopTracer) OnLog(log *Log) {}
+
+// IScope provides tracers with a read-only view of the EVM executing the code.
+type IScope interface {
+	IInspector
+
+	// OpName returns the name of the instruction mapped to the opcode, including custom operations.
+	OpName(opcode OpCode) string
+
+	// ConstantGas returns the static gas of the instruction mapped to the opcode.
+	ConstantGas(opcode OpCode) uint64
+
+	// MemorySize returns the size of the memory, in bytes, without copying it.
+	MemorySize() int
+
+	// Instructions returns a copy of the instruction set executed by the interpreter, including custom operations.
+	Instructions() *InstructionSet
+
+	// Refund returns the gas to refund at the end of the execution.
+	Refund() uint64
+
+	// Address returns the address of the account executing the code.
+	Address() common.Address
+
+	// Code returns a copy of the code being executed.
+	Code() []byte
+
+	// ReturnData returns a copy of the data returned by the execution so far.
+	ReturnData() []byte
+}
+
+// WithTracer sets the tracer invoked by the interpreter.
+func WithTracer(tracer ITracer) Option {
+	return func(e *EVM) {
+		e.tracer = tracer
+	}
+}
+
+// scope restricts an EVM to the read-only methods of IScope.
+// It prevents tracers from modifying the EVM by asserting the underlying type.
+type scope struct {
+	evm *EVM
+}
+
+func (e *EVM) scope() IScope {
+	return &scope{evm: e}
+}
+
+func (s *scope) StackItems() []*uint256.Int {
+	return s.evm.StackItems()
+}
+
+func (s *scope) MemoryBytes() []byte {
+	return s.evm.MemoryBytes()
+}
+
+func (s *scope) MemorySize() int {
+	return s.evm.memory.Size()
+}
+
+func (s *scope) StorageDump() map[int][32]byte {
+	return s.evm.StorageDump()
+}
+
+func (s *scope) PC() int {
+	return s.evm.PC()
+}
+
+func (s *scope) Gas() uint64 {
+	return s.evm.Gas()
+}
+
+func (s *scope) OpName(opcode OpCode) string {
+	return s.evm.instructions.Name(opcode)
+}
+
+func (s *scope) ConstantGas(opcode OpCode) uint64 {
+	return s.evm.instructions.operation(opcode).ConstantGas
+}
+
+func (s *scope) Instructions() *InstructionSet {
+	set := *s.evm.instructions
+	return &set
+}
+
+func (s *scope) Refund() uint64 {
+	return s.evm.state.refund
+}
+
+func (s *scope) Address() common.Address {
+	return s.evm.env.address
+}
+
+func (s *scope) Code() []byte {
+	return append([]byte{}, s.evm.env.code...)
+}
+
+func (s *scope) ReturnData() []byte {
+	return append([]byte{}, s.evm.state.returnData...)
+}
+
+// Report the start of the call frame to the tracer, once.
+func (e *EVM) enter() {
+	if e.state.entered {
+		return
+	}
+	e.state.entered = true
+	if e.tracer != nil {
+		e.tracer.OnEnter(e.env.depth, e.env.caller, e.env.address, append([]byte{}, e.env.input...), e.env.gas, new(uint256.Int).Set(e.env.value))
+	}
+}
+
+// tracingStorage reports the reads and the writes to the underlying storage to the tracer.
+type tracingStorage struct {
+	IStorage
+	evm *EVM
+}
+
+func (s *tracingStorage) Load(key int) [32]byte {
+	value := s.IStorage.Load(key)
+	s.evm.tracer.OnStorageRead(s.evm.env.address, key, value)
+	return value
+}
+
+func (s *tracingStorage) Store(key int, value [32]byte) {
+	prev := s.IStorage.Load(key)
+	s.IStorage.Store(key, value)
+	if prev != value {
+		s.evm.tracer.OnStorageChange(s.evm.env.address, key, prev, value)
+	}
+}
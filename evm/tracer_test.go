@@ -0,0 +1,197 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+)
+
+// recordingTracer records the hooks invoked by the interpreter.
+type recordingTracer struct {
+	events []string
+}
+
+func (t *recordingTracer) OnEnter(depth int, from, to common.Address, input []byte, gas uint64, value *uint256.Int) {
+	t.events = append(t.events, fmt.Sprintf("enter %d %x gas=%d value=%d", depth, input, gas, value))
+}
+
+func (t *recordingTracer) OnOpcodeEnd(pc int, opcode OpCode, gas uint64, scope IScope, depth int) {}
+
+func (t *recordingTracer) OnStorageRead(address common.Address, key int, value [32]byte) {
+	t.events = append(t.events, fmt.Sprintf("storage read %d %x", key, value[31:]))
+}
+
+func (t *recordingTracer) OnStorageChange(address common.Address, key int, prev, value [32]byte) {
+	t.events = append(t.events, fmt.Sprintf("storage %d %x -> %x", key, prev[31:], value[31:]))
+}
+
+func (t *recordingTracer) OnBalanceChange(address common.Address, prev, value *uint256.Int) {
+	t.events = append(t.events, fmt.Sprintf("balance %d -> %d", prev, value))
+}
+
+func (t *recordingTracer) OnLog(log *Log) {
+	t.events = append(t.events, fmt.Sprintf("log %x", log.Data))
+}
+
+func (t *recordingTracer) OnOpcode(pc int, opcode OpCode, gas, cost uint64, scope IScope, returnData []byte, depth int, err error) {
+	t.events = append(t.events, fmt.Sprintf("opcode %d %s gas=%d cost=%d stack=%d err=%v", pc, scope.OpName(opcode), gas, cost, len(scope.StackItems()), err != nil))
+}
+
+func (t *recordingTracer) OnFault(pc int, opcode OpCode, gas, cost uint64, scope IScope, depth int, err error) {
+	t.events = append(t.events, fmt.Sprintf("fault %d %s", pc, scope.OpName(opcode)))
+}
+
+func (t *recordingTracer) OnExit(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+	t.events = append(t.events, fmt.Sprintf("exit %x gasUsed=%d reverted=%t", output, gasUsed, reverted))
+}
+
+func TestTracerHooks(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     []byte
+		expected []string
+	}{
+		{
+			name: "return",
+			// PUSH1 1, PUSH1 0, MSTORE8, PUSH1 1, PUSH1 0, RETURN
+			code: []byte{0x60, 0x01, 0x60, 0x00, 0x53, 0x60, 0x01, 0x60, 0x00, 0xf3},
+			expected: []string{
+				"enter 0  gas=100 value=0",
+				"opcode 0 PUSH1 gas=100 cost=3 stack=0 err=false",
+				"opcode 2 PUSH1 gas=97 cost=3 stack=1 err=false",
+				"opcode 4 MSTORE8 gas=94 cost=6 stack=2 err=false",
+				"opcode 5 PUSH1 gas=88 cost=3 stack=0 err=false",
+				"opcode 7 PUSH1 gas=85 cost=3 stack=1 err=false",
+				"opcode 9 RETURN gas=82 cost=0 stack=2 err=false",
+				"exit 01 gasUsed=18 reverted=false",
+			},
+		},
+		{
+			name: "revert",
+			// PUSH1 0, PUSH1 0, REVERT
+			code: []byte{0x60, 0x00, 0x60, 0x00, 0xfd},
+			expected: []string{
+				"enter 0  gas=100 value=0",
+				"opcode 0 PUSH1 gas=100 cost=3 stack=0 err=false",
+				"opcode 2 PUSH1 gas=97 cost=3 stack=1 err=false",
+				"opcode 4 REVERT gas=94 cost=0 stack=2 err=false",
+				"fault 4 REVERT",
+				"exit  gasUsed=6 reverted=true",
+			},
+		},
+		{
+			name: "stack underflow",
+			code: []byte{0x01},
+			expected: []string{
+				"enter 0  gas=100 value=0",
+				"opcode 0 ADD gas=100 cost=3 stack=0 err=true",
+				"exit  gasUsed=100 reverted=false",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tracer := &recordingTracer{}
+			NewEVM(test.code, WithGas(100), WithTracer(tracer)).Run()
+			if !reflect.DeepEqual(tracer.events, test.expected) {
+				t.Errorf("Unexpected events:\n%q\nwanted:\n%q", tracer.events, test.expected)
+			}
+		})
+	}
+}
+
+func TestTracerScopeIsReadOnly(t *testing.T) {
+	var scope IScope = NewEVM(nil).(*EVM).scope()
+	if _, ok := scope.(IEVM); ok {
+		t.Errorf("The scope should not expose the EVM")
+	}
+}
+
+func TestTracerScopeMemorySize(t *testing.T) {
+	// PUSH1 1, PUSH1 33, MSTORE8
+	evm := NewEVM([]byte{0x60, 0x01, 0x60, 0x21, 0x53})
+	evm.Run()
+	scope := evm.(*EVM).scope()
+	if size := scope.MemorySize(); size != 64 || size != len(scope.MemoryBytes()) {
+		t.Errorf("MemorySize() returned %d, wanted: 64", size)
+	}
+}
+
+func TestTracerReportsCustomOperation(t *testing.T) {
+	tracer := &recordingTracer{}
+	evm := NewEVM([]byte{0xef}, WithGas(100), WithTracer(tracer))
+	evm.RegisterOperation(0xef, &Operation{
+		Name:    "FAIL",
+		Execute: func(ctx *OperationContext) error { return errors.New("failure") },
+	})
+	evm.Run()
+
+	expected := []string{
+		"enter 0  gas=100 value=0",
+		"opcode 0 FAIL gas=100 cost=0 stack=0 err=false",
+		"fault 0 FAIL",
+		"exit  gasUsed=100 reverted=false",
+	}
+	if !reflect.DeepEqual(tracer.events, expected) {
+		t.Errorf("Unexpected events:\n%q\nwanted:\n%q", tracer.events, expected)
+	}
+}
+
+func TestTracerReportsLogs(t *testing.T) {
+	// PUSH1 0xaa, PUSH1 0, MSTORE8, PUSH1 1, PUSH1 0, LOG0
+	tracer := &recordingTracer{}
+	NewEVM([]byte{0x60, 0xaa, 0x60, 0x00, 0x53, 0x60, 0x01, 0x60, 0x00, 0xa0}, WithTracer(tracer)).Run()
+
+	if event := tracer.events[len(tracer.events)-3]; event != "log aa" {
+		t.Errorf("Unexpected event before the last instruction: %q, wanted: %q", event, "log aa")
+	}
+}
+
+func TestTracerReportsStateChanges(t *testing.T) {
+	tracer := &recordingTracer{}
+	evm := NewEVM([]byte{0xef}, WithInput([]byte{0x01}), WithValue(uint256.NewInt(5)), WithTracer(tracer))
+	evm.RegisterOperation(0xef, &Operation{
+		Name: "STATE",
+		Execute: func(ctx *OperationContext) error {
+			ctx.Storage.Store(1, [32]byte{31: 0x01})
+			// Writing the same value again is not a change.
+			ctx.Storage.Store(1, [32]byte{31: 0x01})
+			ctx.Storage.Store(1, [32]byte{31: 0x02})
+			ctx.Storage.Load(1)
+			ctx.ReportBalanceChange(common.Address{}, uint256.NewInt(10), uint256.NewInt(5))
+			return nil
+		},
+	})
+	evm.Run()
+
+	expected := []string{
+		"enter 0 01 gas=30000000 value=5",
+		"opcode 0 STATE gas=30000000 cost=0 stack=0 err=false",
+		"storage 1 00 -> 01",
+		"storage 1 01 -> 02",
+		"storage read 1 02",
+		"balance 10 -> 5",
+		"opcode 1 STOP gas=30000000 cost=0 stack=0 err=false",
+		"exit  gasUsed=0 reverted=false",
+	}
+	if !reflect.DeepEqual(tracer.events, expected) {
+		t.Errorf("Unexpected events:\n%q\nwanted:\n%q", tracer.events, expected)
+	}
+}
+
+func TestTracerReportsEnterOnCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tracer := &recordingTracer{}
+	NewEVM([]byte{0x00}, WithGas(100), WithTracer(tracer)).RunContext(ctx)
+
+	expected := []string{"enter 0  gas=100 value=0", "exit  gasUsed=100 reverted=false"}
+	if !reflect.DeepEqual(tracer.events, expected) {
+		t.Errorf("Unexpected events:\n%q\nwanted:\n%q", tracer.events, expected)
+	}
+}
@@ -0,0 +1,50 @@
+package evm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// VMError describes an error which halted the execution, along with the state of the EVM when the failing instruction started.
+// It wraps the underlying error, so errors.Is can still be used against the sentinel errors (e.g. ErrStackUnderflow).
+type VMError struct {
+	// Error which halted the execution.
+	Err error
+
+	// Program counter of the failing instruction.
+	PC int
+
+	// Opcode of the failing instruction.
+	OpCode OpCode
+
+	// Name of the failing instruction.
+	OpName string
+
+	// Depth of the call executing the code.
+	Depth int
+
+	// Number of stack items required by the instruction.
+	StackRequired int
+
+	// Maximum number of stack items allowed before the instruction, so the stack doesn't overflow.
+	StackLimit int
+
+	// Number of items on the stack.
+	StackSize int
+
+	// Gas left.
+	Gas uint64
+}
+
+func (e *VMError) Error() string {
+	// Undefined opcodes are named like "opcode 0xef not defined", which reads badly in the middle of the message.
+	name := e.OpName
+	if strings.HasSuffix(name, " not defined") {
+		name = fmt.Sprintf("%#x", int(e.OpCode))
+	}
+	return fmt.Sprintf("%v (pc=%d, op=%s, depth=%d, stack=%d, required=%d, gas=%d)", e.Err, e.PC, name, e.Depth, e.StackSize, e.StackRequired, e.Gas)
+}
+
+func (e *VMError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,72 @@
+package evm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestVMError(t *testing.T) {
+	// PUSH1 1, ADD
+	result := NewEVM([]byte{0x60, 0x01, 0x01}, WithGas(100), WithDepth(2)).Run()
+	if !errors.Is(result.Err, ErrStackUnderflow) {
+		t.Fatalf("Run() returned an unexpected error: %v, wanted: %v", result.Err, ErrStackUnderflow)
+	}
+
+	var vmErr *VMError
+	if !errors.As(result.Err, &vmErr) {
+		t.Fatalf("Run() should return a VMError, got %T", result.Err)
+	}
+	expected := VMError{
+		Err:           ErrStackUnderflow,
+		PC:            2,
+		OpCode:        ADD,
+		OpName:        "ADD",
+		Depth:         2,
+		StackRequired: 2,
+		StackLimit:    1025,
+		StackSize:     1,
+		Gas:           97,
+	}
+	if *vmErr != expected {
+		t.Errorf("Expected %+v, got %+v", expected, *vmErr)
+	}
+
+	message := "stack underflow (pc=2, op=ADD, depth=2, stack=1, required=2, gas=97)"
+	if vmErr.Error() != message {
+		t.Errorf("Error() returned %q, wanted %q", vmErr.Error(), message)
+	}
+}
+
+func TestVMErrorUndefinedOpcode(t *testing.T) {
+	result := NewEVM([]byte{0xef}, WithGas(100)).Run()
+	message := "invalid opcode (pc=0, op=0xef, depth=0, stack=0, required=0, gas=100)"
+	if result.Err == nil || result.Err.Error() != message {
+		t.Errorf("Run() returned an unexpected error: %v, wanted: %s", result.Err, message)
+	}
+
+	var vmErr *VMError
+	if !errors.As(result.Err, &vmErr) || vmErr.OpName != "opcode 0xef not defined" {
+		t.Errorf("The VMError should keep the name of the opcode, got %v", vmErr)
+	}
+}
+
+func TestVMErrorFromOperation(t *testing.T) {
+	// PUSH1 1, JUMP to the immediate data of the PUSH1.
+	result := NewEVM([]byte{0x60, 0x01, 0x56}).Run()
+	if !errors.Is(result.Err, ErrInvalidJump) {
+		t.Fatalf("Run() returned an unexpected error: %v, wanted: %v", result.Err, ErrInvalidJump)
+	}
+
+	var vmErr *VMError
+	if !errors.As(result.Err, &vmErr) || vmErr.PC != 2 || vmErr.OpName != "JUMP" {
+		t.Errorf("Expected a VMError for JUMP at pc 2, got %v", result.Err)
+	}
+}
+
+func TestRevertIsNotWrapped(t *testing.T) {
+	// PUSH1 0, PUSH1 0, REVERT
+	result := NewEVM([]byte{0x60, 0x00, 0x60, 0x00, 0xfd}).Run()
+	if result.Err != ErrExecutionReverted {
+		t.Errorf("Run() returned an unexpected error: %v, wanted: %v", result.Err, ErrExecutionReverted)
+	}
+}
@@ -0,0 +1,95 @@
+package srcmap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Contract represents a contract compiled by solc, with the source maps of its creation and runtime code.
+type Contract struct {
+	Name             string
+	Code             []byte
+	RuntimeCode      []byte
+	SourceMap        *SourceMap
+	RuntimeSourceMap *SourceMap
+}
+
+// standardOutput represents the fields of the solc standard JSON output used to build the source maps.
+type standardOutput struct {
+	Sources map[string]struct {
+		ID int `json:"id"`
+	} `json:"sources"`
+	Contracts map[string]map[string]struct {
+		EVM struct {
+			Bytecode         standardBytecode `json:"bytecode"`
+			DeployedBytecode standardBytecode `json:"deployedBytecode"`
+		} `json:"evm"`
+	} `json:"contracts"`
+}
+
+type standardBytecode struct {
+	Object    string `json:"object"`
+	SourceMap string `json:"sourceMap"`
+}
+
+// LoadStandardJSON loads a contract from a solc standard JSON output file.
+// The contract is identified by its source file and name, e.g. "contracts/Token.sol:Token".
+// The source files are read from disk, relative to the directory of the output file.
+// Missing source files are skipped, leaving their instructions unmapped.
+func LoadStandardJSON(path, contract string) (*Contract, error) {
+	file, name, ok := strings.Cut(contract, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid contract %q, expected <file>:<name>", contract)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var output standardOutput
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, err
+	}
+	compiled, ok := output.Contracts[file][name]
+	if !ok {
+		return nil, fmt.Errorf("contract %q not found in %s", contract, path)
+	}
+
+	// Index the source files by their ID.
+	var sources []*Source
+	for sourceName, source := range output.Sources {
+		if source.ID < 0 {
+			return nil, fmt.Errorf("%w: negative ID %d of source %q", ErrInvalidSourceMap, source.ID, sourceName)
+		}
+		content, err := os.ReadFile(filepath.Join(filepath.Dir(path), sourceName))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for len(sources) <= source.ID {
+			sources = append(sources, nil)
+		}
+		sources[source.ID] = NewSource(sourceName, string(content))
+	}
+
+	result := &Contract{
+		Name:        name,
+		Code:        common.FromHex(compiled.EVM.Bytecode.Object),
+		RuntimeCode: common.FromHex(compiled.EVM.DeployedBytecode.Object),
+	}
+	if result.SourceMap, err = New(compiled.EVM.Bytecode.SourceMap, result.Code, sources); err != nil {
+		return nil, err
+	}
+	if result.RuntimeSourceMap, err = New(compiled.EVM.DeployedBytecode.SourceMap, result.RuntimeCode, sources); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,185 @@
+// Package srcmap maps the instructions of a contract compiled by solc to locations in its source files.
+package srcmap
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go-evm/evm"
+)
+
+// ErrInvalidSourceMap is returned when a source map can't be parsed.
+var ErrInvalidSourceMap = errors.New("invalid source map")
+
+// Entry represents the source range of an instruction, as encoded by solc.
+type Entry struct {
+	// Byte offset of the range in the source file.
+	Start int
+
+	// Length of the range, in bytes.
+	Length int
+
+	// Index of the source file, or -1 if the instruction isn't mapped to a source file.
+	File int
+
+	// Jump type: 'i' into a function, 'o' out of a function, or '-' for a regular jump.
+	Jump byte
+
+	// Depth of the modifiers executing the instruction.
+	ModifierDepth int
+}
+
+// Parse decodes a compressed solc source map, e.g. the `sourceMap` of the bytecode in the standard JSON output.
+// It returns one entry per instruction.
+func Parse(s string) ([]Entry, error) {
+	if s == "" {
+		return nil, nil
+	}
+	entries := make([]Entry, 0, strings.Count(s, ";")+1)
+	current := Entry{File: -1, Jump: '-'}
+	for i, element := range strings.Split(s, ";") {
+		// Empty fields, and missing fields at the end, keep the value of the previous entry.
+		for field, value := range strings.Split(element, ":") {
+			if value == "" {
+				continue
+			}
+			if field == 3 {
+				if len(value) != 1 || !strings.Contains("io-", value) {
+					return nil, fmt.Errorf("%w: invalid jump type %q in entry %d", ErrInvalidSourceMap, value, i)
+				}
+				current.Jump = value[0]
+				continue
+			}
+			// Only the source file can be -1, for instructions which aren't mapped to a source file.
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 && (field != 2 || n != -1) {
+				return nil, fmt.Errorf("%w: invalid field %q in entry %d", ErrInvalidSourceMap, value, i)
+			}
+			switch field {
+			case 0:
+				current.Start = n
+			case 1:
+				current.Length = n
+			case 2:
+				current.File = n
+			case 4:
+				current.ModifierDepth = n
+			default:
+				return nil, fmt.Errorf("%w: too many fields in entry %d", ErrInvalidSourceMap, i)
+			}
+		}
+		entries = append(entries, current)
+	}
+	return entries, nil
+}
+
+// Source represents a source file.
+type Source struct {
+	Name    string
+	Content string
+
+	// Byte offset of the start of each line.
+	lines []int
+}
+
+// NewSource creates and returns a new source file.
+func NewSource(name, content string) *Source {
+	lines := []int{0}
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	return &Source{Name: name, Content: content, lines: lines}
+}
+
+// Position returns the line and column, both starting at 1, of a byte offset in the source file.
+func (s *Source) Position(offset int) (line, column int) {
+	line = sort.Search(len(s.lines), func(i int) bool { return s.lines[i] > offset })
+	return line, offset - s.lines[line-1] + 1
+}
+
+// Location represents a range in a source file.
+// Lines and columns start at 1, and the end is exclusive.
+type Location struct {
+	File      string
+	Line      int
+	Column    int
+	EndLine   int
+	EndColumn int
+}
+
+func (l Location) String() string {
+	return fmt.Sprintf("%s:%d:%d", l.File, l.Line, l.Column)
+}
+
+// SourceMap maps the program counters of a code to locations in its source files.
+type SourceMap struct {
+	entries []Entry
+	sources []*Source
+
+	// Index of the instruction at each program counter, or -1 inside immediate data.
+	indices []int
+}
+
+// New creates and returns the source map of the code.
+// The sources are indexed by their solc source ID.
+func New(sourceMap string, code []byte, sources []*Source) (*SourceMap, error) {
+	entries, err := Parse(sourceMap)
+	if err != nil {
+		return nil, err
+	}
+	indices := make([]int, len(code))
+	index := 0
+	for pc := 0; pc < len(code); pc++ {
+		indices[pc] = index
+		index++
+		if opcode := evm.OpCode(code[pc]); opcode >= evm.PUSH1 && opcode <= evm.PUSH32 {
+			for i := 0; i <= int(opcode-evm.PUSH1) && pc+1 < len(code); i++ {
+				pc++
+				indices[pc] = -1
+			}
+		}
+	}
+	return &SourceMap{entries: entries, sources: sources, indices: indices}, nil
+}
+
+// Entry returns the source map entry of the instruction at the program counter.
+// It returns false if the program counter isn't the start of an instruction or has no entry.
+func (m *SourceMap) Entry(pc int) (Entry, bool) {
+	if pc < 0 || pc >= len(m.indices) || m.indices[pc] < 0 || m.indices[pc] >= len(m.entries) {
+		return Entry{}, false
+	}
+	return m.entries[m.indices[pc]], true
+}
+
+// Lookup returns the source location of the instruction at the program counter.
+// It returns false if the instruction isn't mapped to one of the source files, e.g. code generated by the compiler.
+func (m *SourceMap) Lookup(pc int) (Location, bool) {
+	entry, ok := m.Entry(pc)
+	if !ok || entry.File < 0 || entry.File >= len(m.sources) || m.sources[entry.File] == nil {
+		return Location{}, false
+	}
+	source := m.sources[entry.File]
+	if entry.Start+entry.Length > len(source.Content) {
+		return Location{}, false
+	}
+	location := Location{File: source.Name}
+	location.Line, location.Column = source.Position(entry.Start)
+	location.EndLine, location.EndColumn = source.Position(entry.Start + entry.Length)
+	return location, true
+}
+
+// Describe appends the source location of the failing instruction to the message of an execution error.
+func (m *SourceMap) Describe(err error) string {
+	var vmErr *evm.VMError
+	if errors.As(err, &vmErr) {
+		if location, ok := m.Lookup(vmErr.PC); ok {
+			return fmt.Sprintf("%v at %s", err, location)
+		}
+	}
+	return err.Error()
+}
@@ -0,0 +1,130 @@
+package srcmap
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go-evm/evm"
+)
+
+func TestParse(t *testing.T) {
+	entries, err := Parse("1:2:0;:3;;4:5:-1:i:1;::::2;6:7:1:o")
+	if err != nil {
+		t.Fatalf("Parse() returned an unexpected error: %v", err)
+	}
+	expected := []Entry{
+		{Start: 1, Length: 2, File: 0, Jump: '-'},
+		{Start: 1, Length: 3, File: 0, Jump: '-'},
+		{Start: 1, Length: 3, File: 0, Jump: '-'},
+		{Start: 4, Length: 5, File: -1, Jump: 'i', ModifierDepth: 1},
+		{Start: 4, Length: 5, File: -1, Jump: 'i', ModifierDepth: 2},
+		{Start: 6, Length: 7, File: 1, Jump: 'o', ModifierDepth: 2},
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("Unexpected entries: %+v, wanted: %+v", entries, expected)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, s := range []string{"a:1:0", "1:2:0:x", "1:2:0:-:0:9", "-1:5:0", "1:-5:0", "1:2:-2", "1:2:0:-:-1"} {
+		if _, err := Parse(s); !errors.Is(err, ErrInvalidSourceMap) {
+			t.Errorf("Parse(%q) returned an unexpected error: %v, wanted: %v", s, err, ErrInvalidSourceMap)
+		}
+	}
+}
+
+func TestSourcePosition(t *testing.T) {
+	source := NewSource("a.sol", "ab\ncd\n\nef")
+	tests := []struct{ offset, line, column int }{{0, 1, 1}, {1, 1, 2}, {3, 2, 1}, {7, 4, 1}, {9, 4, 3}}
+	for _, test := range tests {
+		if line, column := source.Position(test.offset); line != test.line || column != test.column {
+			t.Errorf("Position(%d) returned %d:%d, wanted %d:%d", test.offset, line, column, test.line, test.column)
+		}
+	}
+}
+
+// testdata/output.json is a hand-written excerpt of the solc standard JSON output for testdata/Counter.sol, not compiler output.
+// Replacing it with the output of `solc --standard-json` requires updating the expected locations below and in the tracers tests.
+func TestLoadStandardJSON(t *testing.T) {
+	contract, err := LoadStandardJSON("testdata/output.json", "Counter.sol:Counter")
+	if err != nil {
+		t.Fatalf("LoadStandardJSON() returned an unexpected error: %v", err)
+	}
+
+	// PUSH1 0x80, PUSH1 0x40, MSTORE, PUSH1 0, DUP1, REVERT
+	m := contract.RuntimeSourceMap
+	if location, ok := m.Lookup(0); !ok || location != (Location{File: "Counter.sol", Line: 4, Column: 1, EndLine: 6, EndColumn: 2}) {
+		t.Errorf("Unexpected location of the first instruction: %+v", location)
+	}
+	if location, ok := m.Lookup(5); !ok || location.String() != "Counter.sol:5:5" || location.EndColumn != 26 {
+		t.Errorf("Unexpected location of the fourth instruction: %+v", location)
+	}
+	if _, ok := m.Lookup(1); ok {
+		t.Errorf("Lookup() should not map immediate data")
+	}
+	if entry, ok := m.Entry(8); !ok || entry.Jump != 'o' {
+		t.Errorf("Unexpected entry of the last instruction: %+v", entry)
+	}
+	if _, ok := m.Lookup(8); ok {
+		t.Errorf("Lookup() should not map instructions without a source file")
+	}
+}
+
+func TestLoadStandardJSONMissingSource(t *testing.T) {
+	data, err := os.ReadFile("testdata/output.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "output.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	contract, err := LoadStandardJSON(path, "Counter.sol:Counter")
+	if err != nil {
+		t.Fatalf("LoadStandardJSON() returned an unexpected error: %v", err)
+	}
+	if _, ok := contract.RuntimeSourceMap.Lookup(0); ok {
+		t.Errorf("Lookup() should not map instructions of a missing source file")
+	}
+	if entry, ok := contract.RuntimeSourceMap.Entry(0); !ok || entry.Start != 57 {
+		t.Errorf("Unexpected entry of the first instruction: %+v", entry)
+	}
+}
+
+func TestLoadStandardJSONNegativeSourceID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "output.json")
+	output := `{"sources": {"Counter.sol": {"id": -1}}, "contracts": {"Counter.sol": {"Counter": {}}}}`
+	if err := os.WriteFile(path, []byte(output), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadStandardJSON(path, "Counter.sol:Counter"); !errors.Is(err, ErrInvalidSourceMap) {
+		t.Errorf("LoadStandardJSON() returned an unexpected error: %v, wanted: %v", err, ErrInvalidSourceMap)
+	}
+}
+
+func TestLoadStandardJSONUnknownContract(t *testing.T) {
+	if _, err := LoadStandardJSON("testdata/output.json", "Counter.sol:Token"); err == nil {
+		t.Errorf("LoadStandardJSON() should fail for an unknown contract")
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	contract, err := LoadStandardJSON("testdata/output.json", "Counter.sol:Counter")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Execute the runtime code without the PUSH1 0 at pc 5, so DUP1 underflows at pc 5.
+	code := append(append([]byte{}, contract.RuntimeCode[:5]...), contract.RuntimeCode[7:]...)
+	result := evm.NewEVM(code).Run()
+	if message := contract.RuntimeSourceMap.Describe(result.Err); message != result.Err.Error()+" at Counter.sol:5:5" {
+		t.Errorf("Unexpected message: %s", message)
+	}
+	if message := contract.RuntimeSourceMap.Describe(evm.ErrExecutionReverted); message != evm.ErrExecutionReverted.Error() {
+		t.Errorf("Unexpected message: %s", message)
+	}
+}
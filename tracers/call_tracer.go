@@ -0,0 +1,172 @@
+package tracers
+
+import (
+	"encoding/json"
+	"errors"
+
+	"go-evm/evm"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/holiman/uint256"
+)
+
+// CallFrame represents a call in the tree built by the call tracer.
+// It is encoded like the frames of the go-ethereum callTracer.
+type CallFrame struct {
+	From         common.Address  `json:"from"`
+	Gas          hexutil.Uint64  `json:"gas"`
+	GasUsed      hexutil.Uint64  `json:"gasUsed"`
+	To           *common.Address `json:"to,omitempty"`
+	Input        hexutil.Bytes   `json:"input"`
+	Output       hexutil.Bytes   `json:"output,omitempty"`
+	Error        string          `json:"error,omitempty"`
+	RevertReason string          `json:"revertReason,omitempty"`
+	Calls        []*CallFrame    `json:"calls,omitempty"`
+	Logs         []*CallLog      `json:"logs,omitempty"`
+	Value        *hexutil.Big    `json:"value,omitempty"`
+	Type         string          `json:"type"`
+}
+
+// CallLog represents an event emitted by a call.
+type CallLog struct {
+	Address common.Address `json:"address"`
+	Topics  []common.Hash  `json:"topics"`
+	Data    hexutil.Bytes  `json:"data"`
+
+	// Number of subcalls made by the call before the event was emitted.
+	Position hexutil.Uint `json:"position"`
+}
+
+// CallTracer builds the tree of the calls made by the execution.
+// Nested calls are executed by EVM instances sharing the tracer, with increasing depths.
+// The EVM has no CALL opcodes, so the run command only traces the top-level call.
+type CallTracer struct {
+	evm.NoopTracer
+
+	withLogs  bool
+	callstack []*CallFrame
+}
+
+// CallTracerOption represents a function that configures a call tracer.
+type CallTracerOption func(*CallTracer)
+
+// NewCallTracer creates and returns a new call tracer.
+func NewCallTracer(opts ...CallTracerOption) *CallTracer {
+	t := &CallTracer{}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// WithLogs attaches the events emitted by each call to its frame.
+// The events of failed calls, and of calls nested in them, are discarded.
+func WithLogs() CallTracerOption {
+	return func(t *CallTracer) {
+		t.withLogs = true
+	}
+}
+
+func (t *CallTracer) OnEnter(depth int, from, to common.Address, input []byte, gas uint64, value *uint256.Int) {
+	// The EVM doesn't distinguish call kinds, so every frame is reported as a CALL.
+	frame := &CallFrame{
+		From:  from,
+		Gas:   hexutil.Uint64(gas),
+		To:    &to,
+		Input: common.CopyBytes(input),
+		Value: (*hexutil.Big)(value.ToBig()),
+		Type:  "CALL",
+	}
+	if depth == 0 {
+		t.callstack = []*CallFrame{frame}
+		return
+	}
+	if len(t.callstack) == 0 {
+		return
+	}
+	t.callstack = append(t.callstack, frame)
+}
+
+func (t *CallTracer) OnExit(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+	if len(t.callstack) == 0 {
+		return
+	}
+	frame := t.callstack[len(t.callstack)-1]
+	frame.GasUsed = hexutil.Uint64(gasUsed)
+	frame.processOutput(output, err)
+	if depth == 0 {
+		if t.withLogs {
+			clearFailedLogs(frame, false)
+		}
+		return
+	}
+	if len(t.callstack) <= 1 {
+		return
+	}
+
+	// Nest the call into its parent.
+	t.callstack = t.callstack[:len(t.callstack)-1]
+	parent := t.callstack[len(t.callstack)-1]
+	parent.Calls = append(parent.Calls, frame)
+}
+
+func (t *CallTracer) OnLog(log *evm.Log) {
+	if !t.withLogs || len(t.callstack) == 0 {
+		return
+	}
+	frame := t.callstack[len(t.callstack)-1]
+	frame.Logs = append(frame.Logs, &CallLog{
+		Address:  log.Address,
+		Topics:   log.Topics,
+		Data:     common.CopyBytes(log.Data),
+		Position: hexutil.Uint(len(frame.Calls)),
+	})
+}
+
+// Result returns the top-level call, once the execution halted.
+func (t *CallTracer) Result() (*CallFrame, error) {
+	if len(t.callstack) != 1 {
+		return nil, errors.New("incorrect number of top-level calls")
+	}
+	return t.callstack[0], nil
+}
+
+// JSON returns the top-level call encoded like the result of the go-ethereum callTracer.
+func (t *CallTracer) JSON() (json.RawMessage, error) {
+	frame, err := t.Result()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(frame)
+}
+
+// Record the output of the call, and its error if it failed.
+// Failed calls only keep their output if they reverted.
+func (f *CallFrame) processOutput(output []byte, err error) {
+	output = common.CopyBytes(output)
+	if err == nil {
+		f.Output = output
+		return
+	}
+	f.Error = errorString(err)
+	if !errors.Is(err, evm.ErrExecutionReverted) || len(output) == 0 {
+		return
+	}
+	f.Output = output
+	if reason, err := abi.UnpackRevert(output); err == nil {
+		f.RevertReason = reason
+	}
+}
+
+// Discard the events of a failed call and of the calls nested in it.
+func clearFailedLogs(f *CallFrame, parentFailed bool) {
+	failed := f.Error != "" || parentFailed
+	if failed {
+		f.Logs = nil
+	}
+	for _, call := range f.Calls {
+		clearFailedLogs(call, failed)
+	}
+}
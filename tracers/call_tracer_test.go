@@ -0,0 +1,113 @@
+package tracers
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"go-evm/evm"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+)
+
+// The address used by the go-ethereum runtime to execute code.
+var contractAddress = common.BytesToAddress([]byte("contract"))
+
+func TestCallTracer(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "return",
+			code:     "602a60005260206000a06001600201600052602060e0f3",
+			input:    "12345678",
+			expected: `{"from":"0x0000000000000000000000000000000000000000","gas":"0x3e8","gasUsed":"0x2b3","to":"0x000000000000000000000000636f6e7472616374","input":"0x12345678","output":"0x0000000000000000000000000000000000000000000000000000000000000000","value":"0x0","type":"CALL"}`,
+		},
+		{
+			name: "revert with reason",
+			// Revert with Error("no")
+			code:     "7f08c379a000000000000000000000000000000000000000000000000000000000600052602060045260026024527f6e6f00000000000000000000000000000000000000000000000000000000000060445260646000fd",
+			expected: `{"from":"0x0000000000000000000000000000000000000000","gas":"0x3e8","gasUsed":"0x36","to":"0x000000000000000000000000636f6e7472616374","input":"0x","output":"0x08c379a0000000000000000000000000000000000000000000000000000000000000002000000000000000000000000000000000000000000000000000000000000000026e6f000000000000000000000000000000000000000000000000000000000000","error":"execution reverted","revertReason":"no","value":"0x0","type":"CALL"}`,
+		},
+		{
+			name:     "invalid opcode",
+			code:     "fe",
+			expected: `{"from":"0x0000000000000000000000000000000000000000","gas":"0x3e8","gasUsed":"0x3e8","to":"0x000000000000000000000000636f6e7472616374","input":"0x","error":"invalid opcode: INVALID","value":"0x0","type":"CALL"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tracer := NewCallTracer()
+			evm.NewEVM(common.FromHex(test.code), evm.WithGas(1000), evm.WithAddress(contractAddress), evm.WithInput(common.FromHex(test.input)), evm.WithTracer(tracer)).Run()
+
+			output, err := tracer.JSON()
+			if err != nil {
+				t.Fatalf("JSON() returned an unexpected error: %v", err)
+			}
+			if string(output) != test.expected {
+				t.Errorf("Unexpected call frame:\n%s\nwanted:\n%s", output, test.expected)
+			}
+		})
+	}
+}
+
+// Register an operation executing the code in a nested call, sharing the tracer of the caller.
+func registerCallOperation(t *testing.T, e evm.IEVM, tracer evm.ITracer, code []byte) {
+	err := e.RegisterOperation(0xef, &evm.Operation{
+		Name: "TESTCALL",
+		Execute: func(ctx *evm.OperationContext) error {
+			callee := common.BytesToAddress([]byte("callee"))
+			nested := evm.NewEVM(code, evm.WithGas(1000), evm.WithDepth(1), evm.WithCaller(contractAddress), evm.WithAddress(callee), evm.WithValue(uint256.NewInt(1)), evm.WithTracer(tracer))
+			nested.Run()
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCallTracerNestedCalls(t *testing.T) {
+	// PUSH1 0, PUSH1 0, LOG0, TESTCALL, PUSH1 0, PUSH1 0, LOG0, TESTCALL
+	code := []byte{0x60, 0x00, 0x60, 0x00, 0xa0, 0xef, 0x60, 0x00, 0x60, 0x00, 0xa0, 0xef}
+	tracer := NewCallTracer(WithLogs())
+	e := evm.NewEVM(code, evm.WithGas(10000), evm.WithAddress(contractAddress), evm.WithTracer(tracer))
+	// PUSH1 0, PUSH1 0, LOG0, INVALID
+	registerCallOperation(t, e, tracer, []byte{0x60, 0x00, 0x60, 0x00, 0xa0, 0xfe})
+	e.Run()
+
+	frame, err := tracer.Result()
+	if err != nil {
+		t.Fatalf("Result() returned an unexpected error: %v", err)
+	}
+	if len(frame.Calls) != 2 {
+		t.Fatalf("Expected 2 nested calls, got %d", len(frame.Calls))
+	}
+	for _, call := range frame.Calls {
+		if call.Error != "invalid opcode: INVALID" || call.GasUsed != 1000 || call.Value.ToInt().Uint64() != 1 || *call.To != common.BytesToAddress([]byte("callee")) {
+			t.Errorf("Unexpected nested call: %+v", call)
+		}
+		if call.Logs != nil {
+			t.Errorf("The logs of a failed call should be discarded, got %d", len(call.Logs))
+		}
+	}
+
+	// The position of a log is the number of calls made before it.
+	if len(frame.Logs) != 2 || frame.Logs[0].Position != 0 || frame.Logs[1].Position != 1 {
+		t.Errorf("Unexpected logs: %+v", frame.Logs)
+	}
+	output, _ := json.Marshal(frame)
+	if !bytes.Contains(output, []byte(`"logs":[{"address":"0x000000000000000000000000636f6e7472616374","topics":[],"data":"0x","position":"0x0"}`)) {
+		t.Errorf("Unexpected encoding of the logs: %s", output)
+	}
+}
+
+func TestCallTracerWithoutExecution(t *testing.T) {
+	if _, err := NewCallTracer().Result(); err == nil {
+		t.Errorf("Result() should fail before the execution")
+	}
+}
@@ -0,0 +1,256 @@
+package tracers
+
+import (
+	"fmt"
+	"io"
+	"slices"
+
+	"go-evm/asm"
+	"go-evm/evm"
+	"go-evm/srcmap"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+// CodeCoverage records the instructions and branches of a code executed over one or more runs.
+type CodeCoverage struct {
+	Code []byte
+
+	// Number of times each instruction was executed, by program counter.
+	Hits map[int]uint64
+
+	// Directions taken by each conditional jump, by program counter.
+	Branches map[int]*BranchCoverage
+
+	// Instruction set which executed the code, including custom operations.
+	instructions *evm.InstructionSet
+}
+
+// BranchCoverage counts the directions taken by a conditional jump.
+type BranchCoverage struct {
+	Taken    uint64
+	NotTaken uint64
+}
+
+// CoverageTracer records the instructions and the branches executed, merged by code hash.
+// The same tracer can be shared by many executions to measure the coverage of a test suite.
+// Branches are the two directions of each JUMPI instruction.
+type CoverageTracer struct {
+	evm.NoopTracer
+
+	coverage   map[common.Hash]*CodeCoverage
+	sourceMaps map[common.Hash]*srcmap.SourceMap
+
+	// Coverage of the code executed by each call frame, from the top-level call.
+	callstack []*CodeCoverage
+	// Program counter of the conditional jump being executed, or -1, and whether its condition is true.
+	pendingJump  int
+	pendingTaken bool
+}
+
+// NewCoverageTracer creates and returns a new coverage tracer.
+func NewCoverageTracer() *CoverageTracer {
+	return &CoverageTracer{
+		coverage:    make(map[common.Hash]*CodeCoverage),
+		sourceMaps:  make(map[common.Hash]*srcmap.SourceMap),
+		pendingJump: -1,
+	}
+}
+
+// AddSourceMap sets the source map of the code, so the report prints the source location of its instructions.
+func (t *CoverageTracer) AddSourceMap(code []byte, sourceMap *srcmap.SourceMap) {
+	t.sourceMaps[crypto.Keccak256Hash(code)] = sourceMap
+}
+
+func (t *CoverageTracer) OnEnter(depth int, from, to common.Address, input []byte, gas uint64, value *uint256.Int) {
+	// The code is only known once the frame executes its first instruction.
+	t.callstack = append(t.callstack, nil)
+}
+
+func (t *CoverageTracer) OnOpcode(pc int, opcode evm.OpCode, gas, cost uint64, scope evm.IScope, returnData []byte, depth int, err error) {
+	if len(t.callstack) == 0 {
+		return
+	}
+	coverage := t.callstack[len(t.callstack)-1]
+	if coverage == nil {
+		coverage = t.codeCoverage(scope.Code())
+		if coverage.instructions == nil {
+			coverage.instructions = scope.Instructions()
+		}
+		t.callstack[len(t.callstack)-1] = coverage
+	}
+
+	// Running past the end of the code executes STOP, which isn't an instruction of the code.
+	if pc >= len(coverage.Code) {
+		return
+	}
+	coverage.Hits[pc]++
+
+	// The direction is only recorded once the jump succeeds.
+	if opcode == evm.JUMPI && err == nil {
+		stack := scope.StackItems()
+		t.pendingJump = pc
+		t.pendingTaken = len(stack) >= 2 && !stack[len(stack)-2].IsZero()
+	}
+}
+
+func (t *CoverageTracer) OnOpcodeEnd(pc int, opcode evm.OpCode, gas uint64, scope evm.IScope, depth int) {
+	if t.pendingJump < 0 || len(t.callstack) == 0 {
+		return
+	}
+	branch := t.callstack[len(t.callstack)-1].branch(t.pendingJump)
+	if t.pendingTaken {
+		branch.Taken++
+	} else {
+		branch.NotTaken++
+	}
+	t.pendingJump = -1
+}
+
+func (t *CoverageTracer) OnFault(pc int, opcode evm.OpCode, gas, cost uint64, scope evm.IScope, depth int, err error) {
+	t.pendingJump = -1
+}
+
+func (t *CoverageTracer) OnExit(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+	if len(t.callstack) > 0 {
+		t.callstack = t.callstack[:len(t.callstack)-1]
+	}
+}
+
+// Coverage returns the coverage of each executed code, by code hash.
+func (t *CoverageTracer) Coverage() map[common.Hash]*CodeCoverage {
+	return t.coverage
+}
+
+// Merge adds the coverage recorded by another tracer to this one.
+func (t *CoverageTracer) Merge(other *CoverageTracer) {
+	for _, coverage := range other.coverage {
+		merged := t.codeCoverage(coverage.Code)
+		if merged.instructions == nil {
+			merged.instructions = coverage.instructions
+		}
+		for pc, hits := range coverage.Hits {
+			merged.Hits[pc] += hits
+		}
+		for pc, branch := range coverage.Branches {
+			mergedBranch := merged.branch(pc)
+			mergedBranch.Taken += branch.Taken
+			mergedBranch.NotTaken += branch.NotTaken
+		}
+	}
+}
+
+// WriteReport writes the coverage percentages of each executed code, followed by its disassembly annotated with the hit counts.
+// Instructions which were never executed are marked with a dash.
+// The source location of the instructions is printed after them if the code has a source map.
+func (t *CoverageTracer) WriteReport(w io.Writer) error {
+	hashes := make([]common.Hash, 0, len(t.coverage))
+	for hash := range t.coverage {
+		hashes = append(hashes, hash)
+	}
+	slices.SortFunc(hashes, func(a, b common.Hash) int { return a.Cmp(b) })
+
+	for _, hash := range hashes {
+		coverage := t.coverage[hash]
+		executed, total := coverage.InstructionCoverage()
+		covered, directions := coverage.BranchCoverage()
+		fmt.Fprintf(w, "%s: instructions %d/%d (%s), branches %d/%d (%s)\n", hash.Hex(), executed, total, percentage(executed, total), covered, directions, percentage(covered, directions))
+		for _, instruction := range coverage.disassemble() {
+			hits := "-"
+			if n, ok := coverage.Hits[instruction.PC]; ok {
+				hits = fmt.Sprint(n)
+			}
+			line := fmt.Sprintf("%8s  %04x: %s", hits, instruction.PC, instruction.Mnemonic)
+			if len(instruction.Immediate) > 0 {
+				line += fmt.Sprintf(" %#x", instruction.Immediate)
+			}
+			if instruction.OpCode == evm.JUMPI {
+				branch := coverage.branch(instruction.PC)
+				line += fmt.Sprintf("  [taken %d, not taken %d]", branch.Taken, branch.NotTaken)
+			}
+			if sourceMap, ok := t.sourceMaps[hash]; ok {
+				if location, ok := sourceMap.Lookup(instruction.PC); ok {
+					line += "  // " + location.String()
+				}
+			}
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// InstructionCoverage returns the number of instructions executed at least once, and the number of instructions in the code.
+func (c *CodeCoverage) InstructionCoverage() (executed, total int) {
+	for _, instruction := range c.disassemble() {
+		if c.Hits[instruction.PC] > 0 {
+			executed++
+		}
+		total++
+	}
+	return executed, total
+}
+
+// BranchCoverage returns the number of branch directions taken at least once, and the number of branch directions in the code.
+// Each conditional jump has two directions.
+func (c *CodeCoverage) BranchCoverage() (covered, total int) {
+	for _, instruction := range c.disassemble() {
+		if instruction.OpCode != evm.JUMPI {
+			continue
+		}
+		total += 2
+		if branch, ok := c.Branches[instruction.PC]; ok {
+			if branch.Taken > 0 {
+				covered++
+			}
+			if branch.NotTaken > 0 {
+				covered++
+			}
+		}
+	}
+	return covered, total
+}
+
+// Return the coverage of the code, creating it if it was never executed.
+func (t *CoverageTracer) codeCoverage(code []byte) *CodeCoverage {
+	hash := crypto.Keccak256Hash(code)
+	coverage, ok := t.coverage[hash]
+	if !ok {
+		coverage = &CodeCoverage{
+			Code:     code,
+			Hits:     make(map[int]uint64),
+			Branches: make(map[int]*BranchCoverage),
+		}
+		t.coverage[hash] = coverage
+	}
+	return coverage
+}
+
+// Return the coverage of the conditional jump at the given program counter, creating it if needed.
+func (c *CodeCoverage) branch(pc int) *BranchCoverage {
+	branch, ok := c.Branches[pc]
+	if !ok {
+		branch = &BranchCoverage{}
+		c.Branches[pc] = branch
+	}
+	return branch
+}
+
+// Split the code into instructions, decoded by the instruction set which executed it.
+func (c *CodeCoverage) disassemble() []asm.Instruction {
+	if c.instructions == nil {
+		return asm.Disassemble(c.Code)
+	}
+	return asm.Disassemble(c.Code, asm.WithInstructionSet(c.instructions))
+}
+
+// Format a ratio as a percentage with one decimal.
+func percentage(n, total int) string {
+	if total == 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(n)/float64(total))
+}
@@ -0,0 +1,132 @@
+package tracers
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"go-evm/evm"
+	"go-evm/srcmap"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// The code jumps to the end if the input is not zero.
+// PUSH0, CALLDATALOAD, PUSH1 9, JUMPI, PUSH1 1, POP, STOP, JUMPDEST, PUSH2 0xffff, STOP
+var coverageCode = common.FromHex("5f35600957600150005b61ffff00")
+
+// Execute the code with a single byte of input.
+func runCoverage(t *testing.T, tracer *CoverageTracer, input byte) {
+	runCoverageCode(t, tracer, coverageCode, input)
+}
+
+func runCoverageCode(t *testing.T, tracer *CoverageTracer, code []byte, input byte) {
+	result := evm.NewEVM(code, evm.WithInput([]byte{input}), evm.WithTracer(tracer)).Run()
+	if result.Err != nil {
+		t.Fatalf("Run() returned an unexpected error: %v", result.Err)
+	}
+}
+
+func TestCoverageTracer(t *testing.T) {
+	tracer := NewCoverageTracer()
+	runCoverage(t, tracer, 0)
+	runCoverage(t, tracer, 0)
+
+	coverage := tracer.Coverage()[crypto.Keccak256Hash(coverageCode)]
+	if coverage == nil {
+		t.Fatalf("The coverage of the code should be recorded")
+	}
+	expectedHits := map[int]uint64{0: 2, 1: 2, 2: 2, 4: 2, 5: 2, 7: 2, 8: 2}
+	if !reflect.DeepEqual(coverage.Hits, expectedHits) {
+		t.Errorf("Unexpected hits: %v, wanted: %v", coverage.Hits, expectedHits)
+	}
+	if branch := coverage.Branches[4]; branch == nil || *branch != (BranchCoverage{NotTaken: 2}) {
+		t.Errorf("Unexpected branch: %+v", branch)
+	}
+	if executed, total := coverage.InstructionCoverage(); executed != 7 || total != 10 {
+		t.Errorf("Unexpected instruction coverage: %d/%d, wanted: 7/10", executed, total)
+	}
+	if covered, total := coverage.BranchCoverage(); covered != 1 || total != 2 {
+		t.Errorf("Unexpected branch coverage: %d/%d, wanted: 1/2", covered, total)
+	}
+}
+
+func TestCoverageTracerJumpToNextInstruction(t *testing.T) {
+	// PUSH0, CALLDATALOAD, PUSH1 5, JUMPI, JUMPDEST, STOP: both directions continue at the next instruction.
+	code := common.FromHex("5f356005575b00")
+	tracer := NewCoverageTracer()
+	runCoverageCode(t, tracer, code, 1)
+	runCoverageCode(t, tracer, code, 0)
+
+	coverage := tracer.Coverage()[crypto.Keccak256Hash(code)]
+	if branch := coverage.Branches[4]; branch == nil || *branch != (BranchCoverage{Taken: 1, NotTaken: 1}) {
+		t.Errorf("Unexpected branch: %+v", branch)
+	}
+}
+
+func TestCoverageTracerMerge(t *testing.T) {
+	tracer, other := NewCoverageTracer(), NewCoverageTracer()
+	runCoverage(t, tracer, 0)
+	runCoverage(t, other, 1)
+	tracer.Merge(other)
+
+	coverage := tracer.Coverage()[crypto.Keccak256Hash(coverageCode)]
+	if branch := coverage.Branches[4]; *branch != (BranchCoverage{Taken: 1, NotTaken: 1}) {
+		t.Errorf("Unexpected branch: %+v", branch)
+	}
+	if executed, total := coverage.InstructionCoverage(); executed != 10 || total != 10 {
+		t.Errorf("Unexpected instruction coverage: %d/%d, wanted: 10/10", executed, total)
+	}
+}
+
+func TestCoverageTracerReport(t *testing.T) {
+	tracer := NewCoverageTracer()
+	runCoverage(t, tracer, 1)
+
+	var output bytes.Buffer
+	if err := tracer.WriteReport(&output); err != nil {
+		t.Fatal(err)
+	}
+	expected := crypto.Keccak256Hash(coverageCode).Hex() + `: instructions 7/10 (70.0%), branches 1/2 (50.0%)
+       1  0000: PUSH0
+       1  0001: CALLDATALOAD
+       1  0002: PUSH1 0x09
+       1  0004: JUMPI  [taken 1, not taken 0]
+       -  0005: PUSH1 0x01
+       -  0007: POP
+       -  0008: STOP
+       1  0009: JUMPDEST
+       1  000a: PUSH2 0xffff
+       1  000d: STOP
+`
+	if output.String() != expected {
+		t.Errorf("Unexpected report:\n%s\nwanted:\n%s", output.String(), expected)
+	}
+}
+
+func TestCoverageTracerReportWithSourceMap(t *testing.T) {
+	contract, err := srcmap.LoadStandardJSON("../srcmap/testdata/output.json", "Counter.sol:Counter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer := NewCoverageTracer()
+	tracer.AddSourceMap(contract.RuntimeCode, contract.RuntimeSourceMap)
+	evm.NewEVM(contract.RuntimeCode, evm.WithTracer(tracer)).Run()
+
+	var output bytes.Buffer
+	if err := tracer.WriteReport(&output); err != nil {
+		t.Fatal(err)
+	}
+	expected := crypto.Keccak256Hash(contract.RuntimeCode).Hex() + `: instructions 6/6 (100.0%), branches 0/0 (n/a)
+       1  0000: PUSH1 0x80  // Counter.sol:4:1
+       1  0002: PUSH1 0x40  // Counter.sol:4:1
+       1  0004: MSTORE  // Counter.sol:4:1
+       1  0005: PUSH1 0x00  // Counter.sol:5:5
+       1  0007: DUP1  // Counter.sol:5:5
+       1  0008: REVERT
+`
+	if output.String() != expected {
+		t.Errorf("Unexpected report:\n%s\nwanted:\n%s", output.String(), expected)
+	}
+}
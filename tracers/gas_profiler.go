@@ -0,0 +1,260 @@
+package tracers
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"go-evm/evm"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/holiman/uint256"
+)
+
+// GasProfile aggregates the gas spent by a group of instructions.
+// The gas is split between the static gas, the memory expansion gas and the remaining dynamic gas.
+type GasProfile struct {
+	Count      uint64
+	Gas        uint64
+	StaticGas  uint64
+	DynamicGas uint64
+	MemoryGas  uint64
+}
+
+// PCKey identifies an instruction in the code of an account.
+type PCKey struct {
+	Address common.Address
+	PC      int
+}
+
+// FrameKey identifies a call frame by the account executing the code and the function selector of its input.
+// The selector is empty if the input is shorter than 4 bytes.
+type FrameKey struct {
+	Address  common.Address
+	Selector string
+}
+
+func (k FrameKey) String() string {
+	if k.Selector == "" {
+		return k.Address.Hex()
+	}
+	return k.Address.Hex() + ":" + k.Selector
+}
+
+// GasProfiler aggregates the gas spent by the execution per opcode, per instruction and per call frame.
+// The gas of a frame only includes the instructions executed by the frame itself, not by the nested calls.
+type GasProfiler struct {
+	evm.NoopTracer
+
+	opcodes map[string]*GasProfile
+	pcs     map[PCKey]*GasProfile
+	frames  map[FrameKey]*GasProfile
+
+	// Gas spent per folded stack of frames, ending with the opcode name.
+	folded map[string]uint64
+	// Names of the instructions executed at each location.
+	names map[PCKey]string
+
+	callstack []FrameKey
+	pending   *pendingInstruction
+}
+
+// pendingInstruction records an instruction until its memory expansion is known.
+type pendingInstruction struct {
+	key        PCKey
+	name       string
+	cost       uint64
+	staticGas  uint64
+	memorySize int
+}
+
+// NewGasProfiler creates and returns a new gas profiler.
+func NewGasProfiler() *GasProfiler {
+	return &GasProfiler{
+		opcodes: make(map[string]*GasProfile),
+		pcs:     make(map[PCKey]*GasProfile),
+		frames:  make(map[FrameKey]*GasProfile),
+		folded:  make(map[string]uint64),
+		names:   make(map[PCKey]string),
+	}
+}
+
+func (p *GasProfiler) OnEnter(depth int, from, to common.Address, input []byte, gas uint64, value *uint256.Int) {
+	frame := FrameKey{Address: to}
+	if len(input) >= 4 {
+		frame.Selector = hexutil.Encode(input[:4])
+	}
+	p.callstack = append(p.callstack, frame)
+}
+
+func (p *GasProfiler) OnOpcode(pc int, opcode evm.OpCode, gas, cost uint64, scope evm.IScope, returnData []byte, depth int, err error) {
+	instruction := &pendingInstruction{
+		key:        PCKey{Address: scope.Address(), PC: pc},
+		name:       scope.OpName(opcode),
+		cost:       cost,
+		staticGas:  min(scope.ConstantGas(opcode), cost),
+		memorySize: scope.MemorySize(),
+	}
+
+	// An instruction failing before its execution doesn't expand the memory.
+	if err != nil {
+		p.record(instruction, instruction.memorySize)
+		return
+	}
+	p.pending = instruction
+}
+
+func (p *GasProfiler) OnOpcodeEnd(pc int, opcode evm.OpCode, gas uint64, scope evm.IScope, depth int) {
+	p.recordPending(scope)
+}
+
+func (p *GasProfiler) OnFault(pc int, opcode evm.OpCode, gas, cost uint64, scope evm.IScope, depth int, err error) {
+	p.recordPending(scope)
+}
+
+func (p *GasProfiler) OnExit(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+	if len(p.callstack) > 0 {
+		p.callstack = p.callstack[:len(p.callstack)-1]
+	}
+}
+
+// ByOpcode returns the gas spent per opcode name.
+func (p *GasProfiler) ByOpcode() map[string]GasProfile {
+	return copyProfiles(p.opcodes)
+}
+
+// ByPC returns the gas spent per instruction.
+func (p *GasProfiler) ByPC() map[PCKey]GasProfile {
+	return copyProfiles(p.pcs)
+}
+
+// ByFrame returns the gas spent per call frame.
+func (p *GasProfiler) ByFrame() map[FrameKey]GasProfile {
+	return copyProfiles(p.frames)
+}
+
+// WriteTable writes the gas spent per opcode, per instruction and per call frame, sorted by decreasing gas.
+func (p *GasProfiler) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "OPCODE\tCOUNT\tGAS\tSTATIC\tDYNAMIC\tMEMORY")
+	for _, name := range sortedKeys(p.opcodes, strings.Compare) {
+		writeProfile(tw, name, p.opcodes[name])
+	}
+
+	fmt.Fprintln(tw, "\nADDRESS\tPC\tOPCODE\tCOUNT\tGAS\tSTATIC\tDYNAMIC\tMEMORY")
+	for _, key := range sortedKeys(p.pcs, comparePCKeys) {
+		writeProfile(tw, fmt.Sprintf("%s\t%d\t%s", key.Address.Hex(), key.PC, p.names[key]), p.pcs[key])
+	}
+
+	fmt.Fprintln(tw, "\nFRAME\tCOUNT\tGAS\tSTATIC\tDYNAMIC\tMEMORY")
+	for _, key := range sortedKeys(p.frames, func(a, b FrameKey) int { return strings.Compare(a.String(), b.String()) }) {
+		writeProfile(tw, key.String(), p.frames[key])
+	}
+	return tw.Flush()
+}
+
+// WriteFolded writes the gas spent per stack of call frames and opcode, in the folded format used by flame graph tools.
+// Each line lists the frames from the top-level call, separated by semicolons, followed by the gas.
+func (p *GasProfiler) WriteFolded(w io.Writer) error {
+	stacks := make([]string, 0, len(p.folded))
+	for stack := range p.folded {
+		stacks = append(stacks, stack)
+	}
+	slices.Sort(stacks)
+	for _, stack := range stacks {
+		if _, err := fmt.Fprintf(w, "%s %d\n", stack, p.folded[stack]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Record the pending instruction, once the memory is expanded.
+func (p *GasProfiler) recordPending(scope evm.IScope) {
+	if p.pending == nil {
+		return
+	}
+	p.record(p.pending, scope.MemorySize())
+	p.pending = nil
+}
+
+// Add the gas spent by the instruction to the profiles.
+func (p *GasProfiler) record(instruction *pendingInstruction, memorySize int) {
+	var memoryGas uint64
+	if memorySize > instruction.memorySize {
+		memoryGas = evm.MemoryGasCost(uint64(memorySize)) - evm.MemoryGasCost(uint64(instruction.memorySize))
+	}
+	memoryGas = min(memoryGas, instruction.cost-instruction.staticGas)
+	profile := GasProfile{
+		Count:      1,
+		Gas:        instruction.cost,
+		StaticGas:  instruction.staticGas,
+		DynamicGas: instruction.cost - instruction.staticGas - memoryGas,
+		MemoryGas:  memoryGas,
+	}
+
+	addProfile(p.opcodes, instruction.name, profile)
+	addProfile(p.pcs, instruction.key, profile)
+	p.names[instruction.key] = instruction.name
+	if len(p.callstack) == 0 {
+		return
+	}
+	addProfile(p.frames, p.callstack[len(p.callstack)-1], profile)
+
+	frames := make([]string, len(p.callstack), len(p.callstack)+1)
+	for i, frame := range p.callstack {
+		frames[i] = frame.String()
+	}
+	p.folded[strings.Join(append(frames, instruction.name), ";")] += instruction.cost
+}
+
+func addProfile[K comparable](profiles map[K]*GasProfile, key K, profile GasProfile) {
+	total, ok := profiles[key]
+	if !ok {
+		total = &GasProfile{}
+		profiles[key] = total
+	}
+	total.Count += profile.Count
+	total.Gas += profile.Gas
+	total.StaticGas += profile.StaticGas
+	total.DynamicGas += profile.DynamicGas
+	total.MemoryGas += profile.MemoryGas
+}
+
+func copyProfiles[K comparable](profiles map[K]*GasProfile) map[K]GasProfile {
+	result := make(map[K]GasProfile, len(profiles))
+	for key, profile := range profiles {
+		result[key] = *profile
+	}
+	return result
+}
+
+// Return the keys of the profiles sorted by decreasing gas, then by key.
+func sortedKeys[K comparable](profiles map[K]*GasProfile, compare func(a, b K) int) []K {
+	keys := make([]K, 0, len(profiles))
+	for key := range profiles {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b K) int {
+		if c := cmp.Compare(profiles[b].Gas, profiles[a].Gas); c != 0 {
+			return c
+		}
+		return compare(a, b)
+	})
+	return keys
+}
+
+func comparePCKeys(a, b PCKey) int {
+	if c := strings.Compare(a.Address.Hex(), b.Address.Hex()); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.PC, b.PC)
+}
+
+func writeProfile(w io.Writer, key string, profile *GasProfile) {
+	fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\n", key, profile.Count, profile.Gas, profile.StaticGas, profile.DynamicGas, profile.MemoryGas)
+}
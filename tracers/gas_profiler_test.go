@@ -0,0 +1,90 @@
+package tracers
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-evm/evm"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Profile code storing a word at offset 0x40 and hashing the first word of the memory.
+// PUSH1 1, PUSH1 0x40, MSTORE, PUSH1 0x20, PUSH1 0, KECCAK256, STOP
+func runGasProfiler(t *testing.T) *GasProfiler {
+	profiler := NewGasProfiler()
+	code := common.FromHex("600160405260206000200000")
+	evm.NewEVM(code, evm.WithAddress(contractAddress), evm.WithInput(common.FromHex("12345678ff")), evm.WithTracer(profiler)).Run()
+	return profiler
+}
+
+func TestGasProfilerByOpcode(t *testing.T) {
+	expected := map[string]GasProfile{
+		"PUSH1":     {Count: 4, Gas: 12, StaticGas: 12},
+		"MSTORE":    {Count: 1, Gas: 12, StaticGas: 3, MemoryGas: 9},
+		"KECCAK256": {Count: 1, Gas: 36, StaticGas: 30, DynamicGas: 6},
+		"STOP":      {Count: 1},
+	}
+	if profiles := runGasProfiler(t).ByOpcode(); !reflect.DeepEqual(profiles, expected) {
+		t.Errorf("Unexpected profiles: %+v, wanted: %+v", profiles, expected)
+	}
+}
+
+func TestGasProfilerByPCAndFrame(t *testing.T) {
+	profiler := runGasProfiler(t)
+	if profile := profiler.ByPC()[PCKey{Address: contractAddress, PC: 4}]; profile != (GasProfile{Count: 1, Gas: 12, StaticGas: 3, MemoryGas: 9}) {
+		t.Errorf("Unexpected profile of MSTORE: %+v", profile)
+	}
+
+	frame := FrameKey{Address: contractAddress, Selector: "0x12345678"}
+	expected := GasProfile{Count: 7, Gas: 60, StaticGas: 45, DynamicGas: 6, MemoryGas: 9}
+	if profiles := profiler.ByFrame(); len(profiles) != 1 || profiles[frame] != expected {
+		t.Errorf("Unexpected frame profiles: %+v, wanted: %+v", profiles, expected)
+	}
+}
+
+func TestGasProfilerFolded(t *testing.T) {
+	var output bytes.Buffer
+	if err := runGasProfiler(t).WriteFolded(&output); err != nil {
+		t.Fatal(err)
+	}
+	expected := `0x000000000000000000000000636F6E7472616374:0x12345678;KECCAK256 36
+0x000000000000000000000000636F6E7472616374:0x12345678;MSTORE 12
+0x000000000000000000000000636F6E7472616374:0x12345678;PUSH1 12
+0x000000000000000000000000636F6E7472616374:0x12345678;STOP 0
+`
+	if output.String() != expected {
+		t.Errorf("Unexpected folded stacks:\n%s\nwanted:\n%s", output.String(), expected)
+	}
+}
+
+func TestGasProfilerTable(t *testing.T) {
+	var output bytes.Buffer
+	if err := runGasProfiler(t).WriteTable(&output); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(output.String(), "\n")
+	expected := []string{
+		"OPCODE     COUNT  GAS  STATIC  DYNAMIC  MEMORY",
+		"KECCAK256  1      36   30      6        0",
+		"MSTORE     1      12   3       0        9",
+		"PUSH1      4      12   12      0        0",
+		"STOP       1      0    0       0        0",
+	}
+	if !reflect.DeepEqual(lines[:len(expected)], expected) {
+		t.Errorf("Unexpected table:\n%s", output.String())
+	}
+}
+
+func TestGasProfilerFailedInstruction(t *testing.T) {
+	// PUSH1 1, ADD
+	profiler := NewGasProfiler()
+	evm.NewEVM(common.FromHex("600101"), evm.WithTracer(profiler)).Run()
+
+	// The static gas of an instruction failing before its execution is reported like the JSON tracer does.
+	if profile := profiler.ByOpcode()["ADD"]; profile != (GasProfile{Count: 1, Gas: 3, StaticGas: 3}) {
+		t.Errorf("Unexpected profile of ADD: %+v", profile)
+	}
+}
@@ -0,0 +1,195 @@
+package tracers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"go-evm/evm"
+	"go-evm/srcmap"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+// JSONTracer writes an EIP-3155 trace of the execution, one JSON object per line.
+// The output matches the `--trace` output of the go-ethereum `evm` command.
+type JSONTracer struct {
+	evm.NoopTracer
+
+	encoder *json.Encoder
+
+	enableMemory     bool
+	enableReturnData bool
+	disableStack     bool
+
+	sourceMaps map[common.Hash]*srcmap.SourceMap
+	// Source map of the code executed by each call frame, from the top-level call, once resolved.
+	callstack []*jsonFrame
+}
+
+// jsonFrame holds the source map of the code executed by a call frame, resolved on its first instruction.
+type jsonFrame struct {
+	resolved  bool
+	sourceMap *srcmap.SourceMap
+}
+
+// JSONTracerOption represents a function that configures a JSON tracer.
+type JSONTracerOption func(*JSONTracer)
+
+// NewJSONTracer creates and returns a new tracer writing the trace to the given writer.
+func NewJSONTracer(w io.Writer, opts ...JSONTracerOption) *JSONTracer {
+	t := &JSONTracer{encoder: json.NewEncoder(w)}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// WithMemory includes the content of the memory in each step.
+func WithMemory() JSONTracerOption {
+	return func(t *JSONTracer) {
+		t.enableMemory = true
+	}
+}
+
+// WithReturnData includes the return data in each step.
+func WithReturnData() JSONTracerOption {
+	return func(t *JSONTracer) {
+		t.enableReturnData = true
+	}
+}
+
+// WithoutStack excludes the stack from each step.
+func WithoutStack() JSONTracerOption {
+	return func(t *JSONTracer) {
+		t.disableStack = true
+	}
+}
+
+// WithSourceMap adds the source location of each instruction of the code to its step, in a `source` field.
+// The field isn't part of EIP-3155, and is omitted for code without a source map.
+func WithSourceMap(code []byte, sourceMap *srcmap.SourceMap) JSONTracerOption {
+	return func(t *JSONTracer) {
+		if t.sourceMaps == nil {
+			t.sourceMaps = make(map[common.Hash]*srcmap.SourceMap)
+		}
+		t.sourceMaps[crypto.Keccak256Hash(code)] = sourceMap
+	}
+}
+
+// jsonStep represents a single step of an EIP-3155 trace.
+// The fields are ordered like go-ethereum encodes them.
+type jsonStep struct {
+	PC         int                 `json:"pc"`
+	Op         evm.OpCode          `json:"op"`
+	Gas        math.HexOrDecimal64 `json:"gas"`
+	GasCost    math.HexOrDecimal64 `json:"gasCost"`
+	Memory     hexutil.Bytes       `json:"memory,omitempty"`
+	MemorySize int                 `json:"memSize"`
+	Stack      []string            `json:"stack"`
+	ReturnData hexutil.Bytes       `json:"returnData,omitempty"`
+	Depth      int                 `json:"depth"`
+	Refund     uint64              `json:"refund"`
+	OpName     string              `json:"opName"`
+	Error      string              `json:"error,omitempty"`
+	Source     string              `json:"source,omitempty"`
+}
+
+// jsonSummary represents the last line of an EIP-3155 trace.
+type jsonSummary struct {
+	Output  string              `json:"output"`
+	GasUsed math.HexOrDecimal64 `json:"gasUsed"`
+	Error   string              `json:"error,omitempty"`
+}
+
+func (t *JSONTracer) OnOpcode(pc int, opcode evm.OpCode, gas, cost uint64, scope evm.IScope, returnData []byte, depth int, err error) {
+	step := jsonStep{
+		PC:         pc,
+		Op:         opcode,
+		Gas:        math.HexOrDecimal64(gas),
+		GasCost:    math.HexOrDecimal64(cost),
+		MemorySize: scope.MemorySize(),
+		Depth:      depth + 1,
+		Refund:     scope.Refund(),
+		OpName:     scope.OpName(opcode),
+		Error:      errorString(err),
+	}
+	if t.enableMemory {
+		step.Memory = scope.MemoryBytes()
+	}
+	if !t.disableStack {
+		stack := scope.StackItems()
+		step.Stack = make([]string, len(stack))
+		for i, item := range stack {
+			step.Stack[i] = item.Hex()
+		}
+	}
+	if t.enableReturnData {
+		step.ReturnData = returnData
+	}
+	if sourceMap := t.sourceMap(scope); sourceMap != nil {
+		if location, ok := sourceMap.Lookup(pc); ok {
+			step.Source = location.String()
+		}
+	}
+	t.encoder.Encode(step)
+}
+
+func (t *JSONTracer) OnEnter(depth int, from, to common.Address, input []byte, gas uint64, value *uint256.Int) {
+	t.callstack = append(t.callstack, &jsonFrame{})
+}
+
+func (t *JSONTracer) OnFault(pc int, opcode evm.OpCode, gas, cost uint64, scope evm.IScope, depth int, err error) {
+	t.OnOpcode(pc, opcode, gas, cost, scope, nil, depth, err)
+}
+
+func (t *JSONTracer) OnExit(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+	if len(t.callstack) > 0 {
+		t.callstack = t.callstack[:len(t.callstack)-1]
+	}
+	if depth > 0 {
+		return
+	}
+	t.encoder.Encode(jsonSummary{
+		Output:  common.Bytes2Hex(output),
+		GasUsed: math.HexOrDecimal64(gasUsed),
+		Error:   errorString(err),
+	})
+}
+
+// Return the source map of the code executed by the current call frame, or nil.
+func (t *JSONTracer) sourceMap(scope evm.IScope) *srcmap.SourceMap {
+	if len(t.sourceMaps) == 0 || len(t.callstack) == 0 {
+		return nil
+	}
+	frame := t.callstack[len(t.callstack)-1]
+	if !frame.resolved {
+		frame.sourceMap, frame.resolved = t.sourceMaps[crypto.Keccak256Hash(scope.Code())], true
+	}
+	return frame.sourceMap
+}
+
+// Format an error like go-ethereum does, so the traces can be compared line by line.
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	var vmErr *evm.VMError
+	if !errors.As(err, &vmErr) {
+		return err.Error()
+	}
+	switch {
+	case errors.Is(err, evm.ErrStackUnderflow):
+		return fmt.Sprintf("stack underflow (%d <=> %d)", vmErr.StackSize, vmErr.StackRequired)
+	case errors.Is(err, evm.ErrStackOverflow):
+		return fmt.Sprintf("stack limit reached %d (%d)", vmErr.StackSize, vmErr.StackLimit)
+	case errors.Is(err, evm.ErrInvalidOpcode):
+		return fmt.Sprintf("invalid opcode: %s", vmErr.OpName)
+	}
+	return vmErr.Err.Error()
+}
@@ -0,0 +1,108 @@
+package tracers
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+
+	"go-evm/evm"
+	"go-evm/srcmap"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// The expected traces in testdata/json were generated with `evm run --trace --gas <gas> <code>` from go-ethereum v1.16.4.
+func TestJSONTracer(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		gas  uint64
+		opts []JSONTracerOption
+	}{
+		{"log_and_return", "602a60005260206000a06001600201600052602060e0f3", 1000, nil},
+		{"invalid_opcode", "6001ef", 1000, nil},
+		{"designated_invalid", "6001fe", 1000, nil},
+		{"revert", "60016000fd", 1000, nil},
+		{"stack_underflow", "600101", 1000, nil},
+		{"out_of_gas", "600160005200", 5, nil},
+		{"implicit_stop", "6001", 100, nil},
+		{"memory", "60ff60005360016000f3", 1000, []JSONTracerOption{WithMemory()}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expected, err := os.ReadFile(fmt.Sprintf("testdata/json/%s.jsonl", test.name))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var output bytes.Buffer
+			tracer := NewJSONTracer(&output, test.opts...)
+			evm.NewEVM(common.FromHex(test.code), evm.WithGas(test.gas), evm.WithTracer(tracer)).Run()
+			if !bytes.Equal(output.Bytes(), expected) {
+				t.Errorf("Unexpected trace:\n%s\nwanted:\n%s", output.String(), expected)
+			}
+		})
+	}
+}
+
+func TestJSONTracerReturnData(t *testing.T) {
+	// PUSH1 1, PUSH1 0, RETURN
+	var output bytes.Buffer
+	tracer := NewJSONTracer(&output, WithReturnData(), WithoutStack())
+	e := evm.NewEVM(common.FromHex("60016000f3"), evm.WithTracer(tracer))
+	e.Run()
+
+	// The return data is only known once the execution halted, so it never appears in the steps.
+	expected := `{"pc":0,"op":96,"gas":"0x1c9c380","gasCost":"0x3","memSize":0,"stack":null,"depth":1,"refund":0,"opName":"PUSH1"}
+{"pc":2,"op":96,"gas":"0x1c9c37d","gasCost":"0x3","memSize":0,"stack":null,"depth":1,"refund":0,"opName":"PUSH1"}
+{"pc":4,"op":243,"gas":"0x1c9c37a","gasCost":"0x3","memSize":0,"stack":null,"depth":1,"refund":0,"opName":"RETURN"}
+{"output":"00","gasUsed":"0x9"}
+`
+	if output.String() != expected {
+		t.Errorf("Unexpected trace:\n%s\nwanted:\n%s", output.String(), expected)
+	}
+}
+
+func TestJSONTracerCustomOperation(t *testing.T) {
+	var output bytes.Buffer
+	e := evm.NewEVM([]byte{0xef}, evm.WithGas(100), evm.WithTracer(NewJSONTracer(&output)))
+	e.RegisterOperation(0xef, &evm.Operation{
+		Name:        "CUSTOM",
+		ConstantGas: 7,
+		Execute:     func(ctx *evm.OperationContext) error { return nil },
+	})
+	e.Run()
+
+	expected := `{"pc":0,"op":239,"gas":"0x64","gasCost":"0x7","memSize":0,"stack":[],"depth":1,"refund":0,"opName":"CUSTOM"}
+{"pc":1,"op":0,"gas":"0x5d","gasCost":"0x0","memSize":0,"stack":[],"depth":1,"refund":0,"opName":"STOP"}
+{"output":"","gasUsed":"0x7"}
+`
+	if output.String() != expected {
+		t.Errorf("Unexpected trace:\n%s\nwanted:\n%s", output.String(), expected)
+	}
+}
+
+func TestJSONTracerSourceMap(t *testing.T) {
+	contract, err := srcmap.LoadStandardJSON("../srcmap/testdata/output.json", "Counter.sol:Counter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var output bytes.Buffer
+	tracer := NewJSONTracer(&output, WithoutStack(), WithSourceMap(contract.RuntimeCode, contract.RuntimeSourceMap))
+	evm.NewEVM(contract.RuntimeCode, evm.WithGas(100), evm.WithTracer(tracer)).Run()
+
+	// REVERT isn't mapped to a source file.
+	expected := `{"pc":0,"op":96,"gas":"0x64","gasCost":"0x3","memSize":0,"stack":null,"depth":1,"refund":0,"opName":"PUSH1","source":"Counter.sol:4:1"}
+{"pc":2,"op":96,"gas":"0x61","gasCost":"0x3","memSize":0,"stack":null,"depth":1,"refund":0,"opName":"PUSH1","source":"Counter.sol:4:1"}
+{"pc":4,"op":82,"gas":"0x5e","gasCost":"0xc","memSize":0,"stack":null,"depth":1,"refund":0,"opName":"MSTORE","source":"Counter.sol:4:1"}
+{"pc":5,"op":96,"gas":"0x52","gasCost":"0x3","memSize":96,"stack":null,"depth":1,"refund":0,"opName":"PUSH1","source":"Counter.sol:5:5"}
+{"pc":7,"op":128,"gas":"0x4f","gasCost":"0x3","memSize":96,"stack":null,"depth":1,"refund":0,"opName":"DUP1","source":"Counter.sol:5:5"}
+{"pc":8,"op":253,"gas":"0x4c","gasCost":"0x0","memSize":96,"stack":null,"depth":1,"refund":0,"opName":"REVERT"}
+{"pc":8,"op":253,"gas":"0x4c","gasCost":"0x0","memSize":96,"stack":null,"depth":1,"refund":0,"opName":"REVERT","error":"execution reverted"}
+{"output":"","gasUsed":"0x18","error":"execution reverted"}
+`
+	if output.String() != expected {
+		t.Errorf("Unexpected trace:\n%s\nwanted:\n%s", output.String(), expected)
+	}
+}
@@ -0,0 +1,214 @@
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+
+	"go-evm/evm"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+// Account represents the state of an account touched by the execution.
+// It is encoded like the accounts of the go-ethereum prestateTracer.
+type Account struct {
+	Balance  *hexutil.Big  `json:"balance,omitempty"`
+	Code     hexutil.Bytes `json:"code,omitempty"`
+	CodeHash *common.Hash  `json:"codeHash,omitempty"`
+
+	// The EVM has no account state and no operation reports nonces, so the nonce is always zero and omitted.
+	// It is kept for compatibility with the go-ethereum format.
+	Nonce uint64 `json:"nonce,omitempty"`
+
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+// PrestateTracer records the state of the accounts touched by the execution before it started.
+// In diff mode, it also records the state of the modified accounts once the execution halted.
+//
+// The EVM doesn't track balances and nonces, so balances are only known once an operation reports a change, and nonces are never reported.
+// Storage slots are recorded when they are first read or written.
+// Without SLOAD and SSTORE, only custom operations and precompiles access the storage, so the result is not compatible with the go-ethereum prestateTracer.
+type PrestateTracer struct {
+	evm.NoopTracer
+
+	diffMode       bool
+	disableCode    bool
+	disableStorage bool
+
+	pre  map[common.Address]*Account
+	post map[common.Address]*Account
+
+	// Current balances and storage values of the touched accounts.
+	balances map[common.Address]*uint256.Int
+	storage  map[common.Address]map[common.Hash]common.Hash
+}
+
+// PrestateTracerOption represents a function that configures a prestate tracer.
+type PrestateTracerOption func(*PrestateTracer)
+
+// NewPrestateTracer creates and returns a new prestate tracer.
+func NewPrestateTracer(opts ...PrestateTracerOption) *PrestateTracer {
+	t := &PrestateTracer{
+		pre:      make(map[common.Address]*Account),
+		post:     make(map[common.Address]*Account),
+		balances: make(map[common.Address]*uint256.Int),
+		storage:  make(map[common.Address]map[common.Hash]common.Hash),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// WithDiffMode records the state of the modified accounts after the execution, and only keeps the modified accounts in the pre-state.
+func WithDiffMode() PrestateTracerOption {
+	return func(t *PrestateTracer) {
+		t.diffMode = true
+	}
+}
+
+// WithoutCode excludes the code of the accounts.
+func WithoutCode() PrestateTracerOption {
+	return func(t *PrestateTracer) {
+		t.disableCode = true
+	}
+}
+
+// WithoutStorage excludes the storage of the accounts.
+func WithoutStorage() PrestateTracerOption {
+	return func(t *PrestateTracer) {
+		t.disableStorage = true
+	}
+}
+
+func (t *PrestateTracer) OnEnter(depth int, from, to common.Address, input []byte, gas uint64, value *uint256.Int) {
+	t.lookupAccount(from)
+	t.lookupAccount(to)
+}
+
+func (t *PrestateTracer) OnOpcode(pc int, opcode evm.OpCode, gas, cost uint64, scope evm.IScope, returnData []byte, depth int, err error) {
+	if err != nil || t.disableCode {
+		return
+	}
+
+	// The code is known once the account executes its first instruction.
+	account := t.lookupAccount(scope.Address())
+	if account.Code != nil {
+		return
+	}
+	if code := scope.Code(); len(code) > 0 {
+		hash := crypto.Keccak256Hash(code)
+		account.Code, account.CodeHash = code, &hash
+	}
+}
+
+func (t *PrestateTracer) OnStorageRead(address common.Address, key int, value [32]byte) {
+	if t.disableStorage {
+		return
+	}
+	t.recordSlot(address, key, value)
+}
+
+func (t *PrestateTracer) OnStorageChange(address common.Address, key int, prev, value [32]byte) {
+	if t.disableStorage {
+		return
+	}
+	t.recordSlot(address, key, prev)
+	t.storage[address][storageSlot(key)] = value
+}
+
+func (t *PrestateTracer) OnBalanceChange(address common.Address, prev, value *uint256.Int) {
+	account := t.lookupAccount(address)
+	if account.Balance == nil {
+		account.Balance = (*hexutil.Big)(prev.ToBig())
+	}
+	t.balances[address] = new(uint256.Int).Set(value)
+}
+
+func (t *PrestateTracer) OnExit(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+	if depth == 0 && t.diffMode {
+		t.processDiffState()
+	}
+}
+
+// Result returns the state of the touched accounts before the execution, and in diff mode, the state of the modified accounts after it.
+func (t *PrestateTracer) Result() (pre, post map[common.Address]*Account) {
+	return t.pre, t.post
+}
+
+// JSON returns the state of the accounts encoded like the result of the go-ethereum prestateTracer.
+func (t *PrestateTracer) JSON() (json.RawMessage, error) {
+	if t.diffMode {
+		return json.Marshal(struct {
+			Post map[common.Address]*Account `json:"post"`
+			Pre  map[common.Address]*Account `json:"pre"`
+		}{t.post, t.pre})
+	}
+	return json.Marshal(t.pre)
+}
+
+// Add the account to the pre-state if it isn't there yet, and return it.
+func (t *PrestateTracer) lookupAccount(address common.Address) *Account {
+	account, ok := t.pre[address]
+	if !ok {
+		account = &Account{Storage: make(map[common.Hash]common.Hash)}
+		t.pre[address] = account
+	}
+	return account
+}
+
+// Record the value of the slot in the pre-state, and as its current value, if it wasn't accessed yet.
+func (t *PrestateTracer) recordSlot(address common.Address, key int, value common.Hash) {
+	account := t.lookupAccount(address)
+	slot := storageSlot(key)
+	if _, ok := account.Storage[slot]; ok {
+		return
+	}
+	account.Storage[slot] = value
+	if t.storage[address] == nil {
+		t.storage[address] = make(map[common.Hash]common.Hash)
+	}
+	t.storage[address][slot] = value
+}
+
+// Record the post-state of the modified accounts, and remove the unmodified accounts and slots from the pre-state.
+func (t *PrestateTracer) processDiffState() {
+	for address, account := range t.pre {
+		modified := false
+		post := &Account{Storage: make(map[common.Hash]common.Hash)}
+		if balance, ok := t.balances[address]; ok && balance.ToBig().Cmp(account.Balance.ToInt()) != 0 {
+			modified = true
+			post.Balance = (*hexutil.Big)(balance.ToBig())
+		}
+		for slot, value := range account.Storage {
+			// Don't include the empty slots.
+			if value == (common.Hash{}) {
+				delete(account.Storage, slot)
+			}
+			newValue := t.storage[address][slot]
+			if value == newValue {
+				delete(account.Storage, slot)
+				continue
+			}
+			modified = true
+			if newValue != (common.Hash{}) {
+				post.Storage[slot] = newValue
+			}
+		}
+
+		if modified {
+			t.post[address] = post
+		} else {
+			delete(t.pre, address)
+		}
+	}
+}
+
+// Convert a storage key to the 32-byte slot used by go-ethereum.
+func storageSlot(key int) common.Hash {
+	return common.BigToHash(big.NewInt(int64(key)))
+}
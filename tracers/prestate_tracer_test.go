@@ -0,0 +1,70 @@
+package tracers
+
+import (
+	"testing"
+
+	"go-evm/evm"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+)
+
+// Execute code reading and writing the storage and changing the balance of the caller, and return the tracer.
+func runPrestateTracer(t *testing.T, opts ...PrestateTracerOption) *PrestateTracer {
+	tracer := NewPrestateTracer(opts...)
+	caller := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	storage := evm.NewStorage()
+	storage.Store(3, [32]byte{31: 0x07})
+	storage.Store(7, [32]byte{31: 0x09})
+	e := evm.NewEVM([]byte{0xef}, evm.WithAddress(contractAddress), evm.WithCaller(caller), evm.WithStorage(storage), evm.WithTracer(tracer))
+	err := e.RegisterOperation(0xef, &evm.Operation{
+		Name: "TESTSTATE",
+		Execute: func(ctx *evm.OperationContext) error {
+			ctx.Storage.Store(1, [32]byte{31: 0x01})
+			ctx.Storage.Store(1, [32]byte{31: 0x02})
+			ctx.Storage.Store(3, [32]byte{})
+			// Writing a slot back to its previous value is not a modification.
+			ctx.Storage.Store(5, [32]byte{31: 0x01})
+			ctx.Storage.Store(5, [32]byte{})
+			// Slots which are only read are part of the pre-state, but not of the diff.
+			ctx.Storage.Load(7)
+			ctx.Storage.Load(3)
+			ctx.ReportBalanceChange(caller, uint256.NewInt(10), uint256.NewInt(4))
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Run()
+	return tracer
+}
+
+func TestPrestateTracer(t *testing.T) {
+	output, err := runPrestateTracer(t).JSON()
+	if err != nil {
+		t.Fatalf("JSON() returned an unexpected error: %v", err)
+	}
+	expected := `{"0x000000000000000000000000636f6e7472616374":{"code":"0xef","codeHash":"0x309b8896ee4c1ff7ec1966155373dee42663b6b40c3fedc70ba501684848d2a3","storage":{"0x0000000000000000000000000000000000000000000000000000000000000001":"0x0000000000000000000000000000000000000000000000000000000000000000","0x0000000000000000000000000000000000000000000000000000000000000003":"0x0000000000000000000000000000000000000000000000000000000000000007","0x0000000000000000000000000000000000000000000000000000000000000005":"0x0000000000000000000000000000000000000000000000000000000000000000","0x0000000000000000000000000000000000000000000000000000000000000007":"0x0000000000000000000000000000000000000000000000000000000000000009"}},"0x1111111111111111111111111111111111111111":{"balance":"0xa"}}`
+	if string(output) != expected {
+		t.Errorf("Unexpected prestate:\n%s\nwanted:\n%s", output, expected)
+	}
+}
+
+func TestPrestateTracerDiffMode(t *testing.T) {
+	output, err := runPrestateTracer(t, WithDiffMode(), WithoutCode()).JSON()
+	if err != nil {
+		t.Fatalf("JSON() returned an unexpected error: %v", err)
+	}
+	expected := `{"post":{"0x000000000000000000000000636f6e7472616374":{"storage":{"0x0000000000000000000000000000000000000000000000000000000000000001":"0x0000000000000000000000000000000000000000000000000000000000000002"}},"0x1111111111111111111111111111111111111111":{"balance":"0x4"}},"pre":{"0x000000000000000000000000636f6e7472616374":{"storage":{"0x0000000000000000000000000000000000000000000000000000000000000003":"0x0000000000000000000000000000000000000000000000000000000000000007"}},"0x1111111111111111111111111111111111111111":{"balance":"0xa"}}}`
+	if string(output) != expected {
+		t.Errorf("Unexpected state diff:\n%s\nwanted:\n%s", output, expected)
+	}
+}
+
+func TestPrestateTracerWithoutStorage(t *testing.T) {
+	pre, _ := runPrestateTracer(t, WithoutStorage()).Result()
+	if account := pre[contractAddress]; account == nil || len(account.Storage) != 0 {
+		t.Errorf("Unexpected account: %+v", account)
+	}
+}